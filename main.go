@@ -2,108 +2,419 @@ package main
 
 import (
 	ctx "context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	certs "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/certs"
+	config "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/config"
+	reload "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/reload"
 	receipt_service "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service"
+	auth "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/auth"
+	ingest "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/ingest"
+	ledger "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/ledger"
+	limits "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/limits"
+	logging "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/logging"
+	metrics "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/metrics"
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+	products "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/products"
+	promotions "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/promotions"
+	rates "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/rates"
+	retailers "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/retailers"
+	supervisor "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/supervisor"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	GRPC_PORT = ":80"
-	HTTP_PORT = ":8081"
+	// Item prices are converted into the base currency using this rate table
+	BASE_CURRENCY = "USD"
+	RATES_FILE    = "receipt-processor/data/rates.csv"
+
+	// Known retailers, which receipts are matched to by name
+	RETAILERS_FILE = "receipt-processor/data/retailers.json"
+
+	// Brands & category keywords, which items are classified by
+	PRODUCTS_FILE = "receipt-processor/data/products.json"
+
+	// Promotions awarding points on top of each receipt's base score
+	PROMOTIONS_FILE = "receipt-processor/data/promotions.json"
+
+	// Points lots past their expiry date are expired this often
+	EXPIRY_INTERVAL = time.Hour
+
+	// Readiness probes fail when the service doesn't report its health this quickly
+	READINESS_TIMEOUT = 2 * time.Second
 )
 
 func main() {
-	// Initialize our loggers
-	il := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
-	el := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
+	// Load our configuration: defaults, then a config file, environment variables & flags
+	cfg, printConfig, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	} else if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	// Initialize our structured logger, which the standard library's & our dependencies' logs go through too
+	logger, err := logging.New(os.Stdout, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
+	}
+	slog.SetDefault(logger)
 
 	// Begin listening
-	lis, err := net.Listen("tcp", GRPC_PORT)
+	lis, err := net.Listen("tcp", cfg.GRPCAddr)
 	if err != nil {
-		el.Fatalf("Failed to begin listening: %v", err)
+		fatal(logger, "Failed to begin listening", err)
 	}
+	gwLis, err := net.Listen("tcp", cfg.HTTPAddr)
+	if err != nil {
+		fatal(logger, "Failed to begin listening for the gateway", err)
+	}
+
+	// Secure both listeners with TLS when configured, reloading certificates as they're renewed
+	serverCreds, dialCreds, gwTLS := insecure.NewCredentials(), insecure.NewCredentials(), (*tls.Config)(nil)
+	if cfg.TLS.Enabled() {
+		serverCreds, dialCreds, gwTLS = loadTLS(cfg.TLS, config.DialAddr(cfg.GRPCAddr), logger)
+	}
+
+	// Load exchange rates, using each currency's first rate for purchases dated before it
+	rateTable, err := rates.LoadFile(RATES_FILE, BASE_CURRENCY)
+	if err != nil {
+		fatal(logger, "Failed to load exchange rates", err)
+	}
+	rateTable.Backfill = true
+
+	// Load known retailers
+	retailerRegistry, err := retailers.LoadFile(RETAILERS_FILE)
+	if err != nil {
+		fatal(logger, "Failed to load retailers", err)
+	}
+
+	// Load the product dictionary
+	itemClassifier, err := products.LoadFile(PRODUCTS_FILE)
+	if err != nil {
+		fatal(logger, "Failed to load product dictionary", err)
+	}
+
+	// Load promotions
+	promotionCatalog, err := promotions.LoadFile(PROMOTIONS_FILE)
+	if err != nil {
+		fatal(logger, "Failed to load promotions", err)
+	}
+
+	// Require API keys or bearer tokens of callers when configured, reloading them as they're rotated
+	serviceOpts := []receipt_service.Option{}
+	authFiles := []reload.Reloadable{}
+	if cfg.Auth.APIKeysFile != "" {
+		keys, err := auth.LoadKeys(cfg.Auth.APIKeysFile)
+		if err != nil {
+			fatal(logger, "Failed to load API keys", err)
+		}
+		authFiles = append(authFiles, keys)
+		serviceOpts = append(serviceOpts, receipt_service.WithAPIKeys(keys))
+	}
+	if cfg.Auth.JWKSFile != "" {
+		jwks, err := auth.LoadJWKS(cfg.Auth.JWKSFile)
+		if err != nil {
+			fatal(logger, "Failed to load JWKS", err)
+		}
+		authFiles = append(authFiles, jwks)
+		serviceOpts = append(serviceOpts, receipt_service.WithBearerTokens(&auth.TokenVerifier{
+			Keys:     jwks,
+			Issuer:   cfg.Auth.Issuer,
+			Audience: cfg.Auth.Audience,
+			Leeway:   time.Duration(cfg.Auth.Leeway),
+		}))
+	}
+	if len(authFiles) > 0 && cfg.Auth.ReloadInterval > 0 {
+		go reload.Watch(ctx.Background(), time.Duration(cfg.Auth.ReloadInterval), reloadLogger(logger), authFiles...)
+	}
+
+	// Limit each client's requests & daily submissions when configured, reloading the limits as they're changed
+	if cfg.Limits.Enabled() {
+		limiter, err := limits.LoadFile(cfg.Limits.File)
+		if err != nil {
+			fatal(logger, "Failed to load rate limits", err)
+		}
+		if cfg.Limits.ReloadInterval > 0 {
+			go reload.Watch(ctx.Background(), time.Duration(cfg.Limits.ReloadInterval), reloadLogger(logger), limiter)
+		}
+		serviceOpts = append(serviceOpts, receipt_service.WithRateLimits(limiter))
+	}
+
+	// Export a span of each request & the work it did when configured; gRPC & HTTP calls are traced either way,
+	// joining traces callers started with a W3C traceparent header, but are only recorded with an exporter
+	stopTracing := startTracing(cfg.Tracing, logger)
+
+	// Expire awarded points on a schedule, in the background
+	pointsLedger := ledger.New()
+	pointsLedger.Logger = logger
+	go pointsLedger.RunExpiry(ctx.Background(), EXPIRY_INTERVAL)
+
+	// Record metrics, served by the gateway for Prometheus to scrape
+	serviceMetrics := metrics.New()
+
+	// Report the service's health, until it's marked NOT_SERVING on shutdown
+	healthServer := health.NewServer()
+
+	// Keep receipts in memory, closing the store once the servers have stopped
+	store := &model.ReceiptDB{Store: make(map[string]*model.Receipt)}
 
-	// Initialize the Receipt Service, DB, info logger, and error logger
-	// We use a goroutine to allow shutdown to proceed in parallel
-	s := receipt_service.NewService()
-	go startServer(lis, s, il, el)
+	// Initialize the Receipt Service & its server, run below alongside the gateway
+	s := receipt_service.NewService(append(serviceOpts,
+		receipt_service.WithStore(store),
+		receipt_service.WithRates(rateTable),
+		receipt_service.WithRetailers(retailerRegistry),
+		receipt_service.WithItemClassifier(itemClassifier),
+		receipt_service.WithValidationPolicy(cfg.Validation.Policy()),
+		receipt_service.WithDateLayouts(cfg.Formats.DateLayouts...),
+		receipt_service.WithTimeLayouts(cfg.Formats.TimeLayouts...),
+		receipt_service.WithLedger(pointsLedger),
+		receipt_service.WithPromotions(promotionCatalog),
+		receipt_service.WithMetrics(serviceMetrics),
+		receipt_service.WithLogger(logger),
+		receipt_service.WithHealth(healthServer),
+		receipt_service.WithServerOptions(grpc.Creds(serverCreds), grpc.StatsHandler(otelgrpc.NewServerHandler())),
+	)...)
 
 	// grpc-gateway to multiplex
-	if conn, err := grpc.NewClient("0.0.0.0"+GRPC_PORT, grpc.WithTransportCredentials(insecure.NewCredentials())); err != nil {
+	if conn, err := grpc.NewClient(config.DialAddr(cfg.GRPCAddr), grpc.WithTransportCredentials(dialCreds), grpc.WithStatsHandler(otelgrpc.NewClientHandler())); err != nil {
 		// everything should explode - gracefully - if we can't reach the server internally
-		el.Fatalln("Failed to dial gRPC server:", err)
+		fatal(logger, "Failed to dial gRPC server", err)
 	} else {
 		// mux!
 		gwmux := runtime.NewServeMux(
 			// default json unmarshaler doesn't handle unmarshaling to proto messages well,
 			// so we'll use one that supports that
 			runtime.WithMarshalerOption("application/json", &runtime.JSONPb{}),
+			// UBL 2.1 e-invoices from our European partners are accepted as receipts
+			runtime.WithMarshalerOption("application/xml", &ingest.UBLMarshaler{}),
+			// API keys may be sent in their own header, as well as the authorization header, & callers may choose request ids
+			runtime.WithIncomingHeaderMatcher(incomingHeaders),
+			// limited clients are told when to retry in a standard Retry-After header, & every client its request id
+			runtime.WithOutgoingHeaderMatcher(outgoingHeaders),
 		)
 
 		// register the server
 		if err = pb.RegisterReceiptServiceHandler(ctx.Background(), gwmux, conn); err != nil {
-			el.Fatalln("Failed to register gateway:", err)
+			fatal(logger, "Failed to register gateway", err)
+		} else if err = gwmux.HandlePath(http.MethodGet, "/metrics", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			serviceMetrics.Handler().ServeHTTP(w, r)
+		}); err != nil {
+			fatal(logger, "Failed to register metrics endpoint", err)
+		} else if err = gwmux.HandlePath(http.MethodGet, "/healthz", healthz); err != nil {
+			fatal(logger, "Failed to register liveness endpoint", err)
+		} else if err = gwmux.HandlePath(http.MethodGet, "/readyz", readyz(healthpb.NewHealthClient(conn))); err != nil {
+			fatal(logger, "Failed to register readiness endpoint", err)
 		} else {
 			gwServer := &http.Server{
-				Addr:    HTTP_PORT,
-				Handler: gwmux,
+				Handler:      otelhttp.NewHandler(gwmux, "gateway", otelhttp.WithSpanNameFormatter(gatewaySpanName)),
+				ReadTimeout:  time.Duration(cfg.Timeouts.Read),
+				WriteTimeout: time.Duration(cfg.Timeouts.Write),
+				IdleTimeout:  time.Duration(cfg.Timeouts.Idle),
+				TLSConfig:    gwTLS,
 			}
 
-			log.Printf("Serving Receipt Service REST API via gRPC-Gateway @ http://0.0.0.0%s", HTTP_PORT)
-			if err := gwServer.ListenAndServe(); err != nil {
-				el.Fatalf("Failed to serve gRPC-Gateway HTTP server for the Receipt Service: %v", err)
+			// Serve both until an OS signal is received, then shut them down gracefully: the gateway,
+			// the gRPC server, then the gateway's connection to it, the trace exporter & the store
+			sup := &supervisor.Supervisor{
+				Server:          s,
+				ServerListener:  lis,
+				Gateway:         gwServer,
+				GatewayListener: gwLis,
+				Health:          healthServer,
+				Closers: []supervisor.Closer{
+					func(ctx.Context) error { return conn.Close() },
+					stopTracing,
+					store.Close,
+				},
+				Timeout: time.Duration(cfg.Timeouts.Shutdown),
+				Logger:  logger,
 			}
+			signals, stop := signal.NotifyContext(ctx.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			if err := sup.Run(signals); err != nil {
+				fatal(logger, "Receipt Service did not run or shut down cleanly", err)
+			}
+		}
+	}
+}
+
+// Install the tracer provider & W3C trace context propagator, returning a function which flushes the exporter
+func startTracing(c config.Tracing, logger *slog.Logger) (stop supervisor.Closer) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !c.Enabled() {
+		return func(ctx.Context) error { return nil }
+	}
+
+	// stdout is for reading along, so its spans are indented; the file gets one span per line
+	var opts []stdouttrace.Option
+	var file *os.File
+	if c.Exporter == "file" {
+		f, err := os.OpenFile(c.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			fatal(logger, "Failed to open trace file", err)
+		}
+		file = f
+		opts = append(opts, stdouttrace.WithWriter(f))
+	} else {
+		opts = append(opts, stdouttrace.WithPrettyPrint())
+	}
+	exporter, err := stdouttrace.New(opts...)
+	if err != nil {
+		fatal(logger, "Failed to create trace exporter", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("receipt-processor"))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	// the trace file is closed once the provider has flushed its last spans to it
+	return func(shutdownCtx ctx.Context) error {
+		err := provider.Shutdown(shutdownCtx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}
+}
+
+// Name the gateway's spans by the method & path requested
+func gatewaySpanName(_ string, r *http.Request) string {
+	return r.Method + " " + r.URL.Path
+}
+
+// Load the configured certificates, watching them for changes, and return the credentials of the gRPC server,
+// of the gateway's connection to it, and the TLS configuration of the gateway itself
+func loadTLS(c config.TLS, dialAddr string, logger *slog.Logger) (server credentials.TransportCredentials, dial credentials.TransportCredentials, gateway *tls.Config) {
+	keypair, err := certs.LoadKeypair(c.CertFile, c.KeyFile)
+	if err != nil {
+		fatal(logger, "Failed to load TLS certificate", err)
+	}
+	reloadables := []reload.Reloadable{keypair}
+
+	// when gRPC callers' certificates are verified, the gateway presents the server's own
+	var clientCAs, rootCAs *certs.Pool
+	var gatewayCert *certs.Keypair
+	if c.ClientCAFile != "" {
+		if clientCAs, err = certs.LoadPool(c.ClientCAFile); err != nil {
+			fatal(logger, "Failed to load TLS client CAs", err)
+		}
+		reloadables = append(reloadables, clientCAs)
+		gatewayCert = keypair
+	}
+	if c.CAFile != "" {
+		if rootCAs, err = certs.LoadPool(c.CAFile); err != nil {
+			fatal(logger, "Failed to load TLS CAs", err)
+		}
+	}
+	serverName := c.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(dialAddr)
+	}
+
+	if c.ReloadInterval > 0 {
+		go reload.Watch(ctx.Background(), time.Duration(c.ReloadInterval), reloadLogger(logger), reloadables...)
+	}
+	server = credentials.NewTLS(certs.ServerConfig(keypair, clientCAs, c.RequireClientCert))
+	dial = credentials.NewTLS(certs.ClientConfig(gatewayCert, rootCAs, serverName))
+	return server, dial, certs.ServerConfig(keypair, nil, false)
+}
+
+// Log an error the service can't start without, then exit
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// Log each reload of a watched file
+func reloadLogger(logger *slog.Logger) func(r reload.Reloadable, err error) {
+	return func(r reload.Reloadable, err error) {
+		if err != nil {
+			logger.Error("Failed to reload, still using the previous version", "file", fmt.Sprint(r), "error", err)
+		} else {
+			logger.Info("Reloaded", "file", fmt.Sprint(r))
 		}
 	}
+}
 
-	// Wait for the server to shut down gracefully when an OS signal is received
-	waitForShutdown(s, il)
+// Forward the HTTP headers the service reads to it as gRPC metadata, alongside the gateway's defaults
+func incomingHeaders(key string) (string, bool) {
+	if strings.EqualFold(key, auth.API_KEY_HEADER) {
+		return auth.API_KEY_HEADER, true
+	} else if strings.EqualFold(key, logging.REQUEST_ID_HEADER) {
+		return logging.REQUEST_ID_HEADER, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
 }
 
-// Start the gRPC server and listen for incoming connections
-func startServer(lis net.Listener, s *grpc.Server, il *log.Logger, el *log.Logger) {
-	il.Println("gRPC Server starting on port 80")
-	if err := s.Serve(lis); err != nil && err != grpc.ErrServerStopped {
-		el.Fatalf("Failed to serve: %v", err)
+// Return the gRPC metadata clients act on as plain HTTP headers, & the rest with the gateway's default prefix
+func outgoingHeaders(key string) (string, bool) {
+	if key == limits.RETRY_AFTER_HEADER {
+		return "Retry-After", true
+	} else if key == logging.REQUEST_ID_HEADER {
+		return "X-Request-ID", true
 	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
 
+// Report the gateway is alive, whether or not the service behind it is ready
+func healthz(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, "ok\n")
 }
 
-// Wait for interrupt signal, then gracefully shut down server
-func waitForShutdown(s *grpc.Server, il *log.Logger) {
-	// Create a channel to receive OS signals
-	sigs := make(chan os.Signal, 1)
-	// Create a channel to receive a signal when server shutdown is complete
-	done := make(chan bool, 1)
-
-	// Register to receive specific signals
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start a goroutine that will listen for signals
-	go func() {
-		sig := <-sigs
-		il.Printf("Received signal: %s", sig)
-
-		// Perform server shutdown
-		il.Println("Shutting down server...")
-		s.GracefulStop() // Gracefully stop the server
-		il.Println("Server has been shut down.")
-
-		// Notify the main goroutine that we're done
-		done <- true
-	}()
-
-	// Wait for shutdown to complete
-	<-done
-	il.Println("Graceful shutdown completed")
+// Report whether the service is reachable through the gateway & serving, having checked its store is ready;
+// it isn't while shutting down, so load balancers stop sending requests before connections drain
+func readyz(client healthpb.HealthClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		c, cancel := ctx.WithTimeout(r.Context(), READINESS_TIMEOUT)
+		defer cancel()
+		w.Header().Set("Content-Type", "text/plain")
+		res, err := client.Check(c, &healthpb.HealthCheckRequest{Service: pb.ReceiptService_ServiceDesc.ServiceName})
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, "unreachable: "+status.Convert(err).Message()+"\n")
+		} else if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, strings.ToLower(res.GetStatus().String())+"\n")
+		} else {
+			io.WriteString(w, "ok\n")
+		}
+	}
 }
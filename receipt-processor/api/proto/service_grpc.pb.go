@@ -31,8 +31,16 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ReceiptService_ProcessReceipt_FullMethodName = "/ashyrae.receipt.ReceiptService/ProcessReceipt"
-	ReceiptService_AwardPoints_FullMethodName    = "/ashyrae.receipt.ReceiptService/AwardPoints"
+	ReceiptService_ProcessReceipt_FullMethodName        = "/ashyrae.receipt.ReceiptService/ProcessReceipt"
+	ReceiptService_ImportEmailReceipt_FullMethodName    = "/ashyrae.receipt.ReceiptService/ImportEmailReceipt"
+	ReceiptService_AwardPoints_FullMethodName           = "/ashyrae.receipt.ReceiptService/AwardPoints"
+	ReceiptService_GetBalance_FullMethodName            = "/ashyrae.receipt.ReceiptService/GetBalance"
+	ReceiptService_ListLedgerEntries_FullMethodName     = "/ashyrae.receipt.ReceiptService/ListLedgerEntries"
+	ReceiptService_RedeemPoints_FullMethodName          = "/ashyrae.receipt.ReceiptService/RedeemPoints"
+	ReceiptService_ReversePoints_FullMethodName         = "/ashyrae.receipt.ReceiptService/ReversePoints"
+	ReceiptService_PreviewExpiringPoints_FullMethodName = "/ashyrae.receipt.ReceiptService/PreviewExpiringPoints"
+	ReceiptService_ListFlaggedReceipts_FullMethodName   = "/ashyrae.receipt.ReceiptService/ListFlaggedReceipts"
+	ReceiptService_ReviewReceipt_FullMethodName         = "/ashyrae.receipt.ReceiptService/ReviewReceipt"
 )
 
 // ReceiptServiceClient is the client API for ReceiptService service.
@@ -44,9 +52,33 @@ type ReceiptServiceClient interface {
 	// ProcessReceipt receives a ProcessRequest containing a Receipt,
 	// and returns a ProcessResponse containing a unique identifying string representing a processed receipt.
 	ProcessReceipt(ctx context.Context, in *ProcessReceiptRequest, opts ...grpc.CallOption) (*ProcessReceiptResponse, error)
+	// ImportEmailReceipt receives an ImportEmailReceiptRequest containing a raw RFC 822 email message,
+	// extracts the e-receipt it carries, and returns an ImportEmailReceiptResponse containing the processed receipt & its identifying string.
+	ImportEmailReceipt(ctx context.Context, in *ImportEmailReceiptRequest, opts ...grpc.CallOption) (*ImportEmailReceiptResponse, error)
 	// AwardPoints receives an AwardPointsRequest containing a unique identifying string representing a processed receipt,
 	// and returns an AwardPointsResponse containing the associated points being awarded.
 	AwardPoints(ctx context.Context, in *AwardPointsRequest, opts ...grpc.CallOption) (*AwardPointsResponse, error)
+	// GetBalance receives a GetBalanceRequest containing a user's identifying string,
+	// and returns a GetBalanceResponse containing the user's running total of awarded points.
+	GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error)
+	// ListLedgerEntries receives a ListLedgerEntriesRequest containing a user's identifying string,
+	// and returns a ListLedgerEntriesResponse containing every posting of points to the user, oldest first.
+	ListLedgerEntries(ctx context.Context, in *ListLedgerEntriesRequest, opts ...grpc.CallOption) (*ListLedgerEntriesResponse, error)
+	// RedeemPoints receives a RedeemPointsRequest containing a user's identifying string & the points they are spending,
+	// and returns a RedeemPointsResponse containing the debit posted to the user's balance.
+	RedeemPoints(ctx context.Context, in *RedeemPointsRequest, opts ...grpc.CallOption) (*RedeemPointsResponse, error)
+	// ReversePoints receives a ReversePointsRequest identifying an earlier award or redemption,
+	// and returns a ReversePointsResponse containing the entry posted to undo it.
+	ReversePoints(ctx context.Context, in *ReversePointsRequest, opts ...grpc.CallOption) (*ReversePointsResponse, error)
+	// PreviewExpiringPoints receives a PreviewExpiringPointsRequest containing a user's identifying string and/or a set of receipts,
+	// and returns a PreviewExpiringPointsResponse containing the points lots which expire within the requested number of days.
+	PreviewExpiringPoints(ctx context.Context, in *PreviewExpiringPointsRequest, opts ...grpc.CallOption) (*PreviewExpiringPointsResponse, error)
+	// ListFlaggedReceipts receives a ListFlaggedReceiptsRequest, optionally naming a fraud review outcome,
+	// and returns a ListFlaggedReceiptsResponse containing the receipts flagged, held or rejected as likely fraudulent, most recent first.
+	ListFlaggedReceipts(ctx context.Context, in *ListFlaggedReceiptsRequest, opts ...grpc.CallOption) (*ListFlaggedReceiptsResponse, error)
+	// ReviewReceipt receives a ReviewReceiptRequest, naming a stored receipt & the review's decision,
+	// and returns a ReviewReceiptResponse once the receipt is released for awarding or rejected & removed, & no longer listed for review.
+	ReviewReceipt(ctx context.Context, in *ReviewReceiptRequest, opts ...grpc.CallOption) (*ReviewReceiptResponse, error)
 }
 
 type receiptServiceClient struct {
@@ -67,6 +99,16 @@ func (c *receiptServiceClient) ProcessReceipt(ctx context.Context, in *ProcessRe
 	return out, nil
 }
 
+func (c *receiptServiceClient) ImportEmailReceipt(ctx context.Context, in *ImportEmailReceiptRequest, opts ...grpc.CallOption) (*ImportEmailReceiptResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportEmailReceiptResponse)
+	err := c.cc.Invoke(ctx, ReceiptService_ImportEmailReceipt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *receiptServiceClient) AwardPoints(ctx context.Context, in *AwardPointsRequest, opts ...grpc.CallOption) (*AwardPointsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(AwardPointsResponse)
@@ -77,6 +119,76 @@ func (c *receiptServiceClient) AwardPoints(ctx context.Context, in *AwardPointsR
 	return out, nil
 }
 
+func (c *receiptServiceClient) GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBalanceResponse)
+	err := c.cc.Invoke(ctx, ReceiptService_GetBalance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receiptServiceClient) ListLedgerEntries(ctx context.Context, in *ListLedgerEntriesRequest, opts ...grpc.CallOption) (*ListLedgerEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLedgerEntriesResponse)
+	err := c.cc.Invoke(ctx, ReceiptService_ListLedgerEntries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receiptServiceClient) RedeemPoints(ctx context.Context, in *RedeemPointsRequest, opts ...grpc.CallOption) (*RedeemPointsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RedeemPointsResponse)
+	err := c.cc.Invoke(ctx, ReceiptService_RedeemPoints_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receiptServiceClient) ReversePoints(ctx context.Context, in *ReversePointsRequest, opts ...grpc.CallOption) (*ReversePointsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReversePointsResponse)
+	err := c.cc.Invoke(ctx, ReceiptService_ReversePoints_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receiptServiceClient) PreviewExpiringPoints(ctx context.Context, in *PreviewExpiringPointsRequest, opts ...grpc.CallOption) (*PreviewExpiringPointsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PreviewExpiringPointsResponse)
+	err := c.cc.Invoke(ctx, ReceiptService_PreviewExpiringPoints_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receiptServiceClient) ListFlaggedReceipts(ctx context.Context, in *ListFlaggedReceiptsRequest, opts ...grpc.CallOption) (*ListFlaggedReceiptsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFlaggedReceiptsResponse)
+	err := c.cc.Invoke(ctx, ReceiptService_ListFlaggedReceipts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receiptServiceClient) ReviewReceipt(ctx context.Context, in *ReviewReceiptRequest, opts ...grpc.CallOption) (*ReviewReceiptResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReviewReceiptResponse)
+	err := c.cc.Invoke(ctx, ReceiptService_ReviewReceipt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ReceiptServiceServer is the server API for ReceiptService service.
 // All implementations must embed UnimplementedReceiptServiceServer
 // for forward compatibility.
@@ -86,9 +198,33 @@ type ReceiptServiceServer interface {
 	// ProcessReceipt receives a ProcessRequest containing a Receipt,
 	// and returns a ProcessResponse containing a unique identifying string representing a processed receipt.
 	ProcessReceipt(context.Context, *ProcessReceiptRequest) (*ProcessReceiptResponse, error)
+	// ImportEmailReceipt receives an ImportEmailReceiptRequest containing a raw RFC 822 email message,
+	// extracts the e-receipt it carries, and returns an ImportEmailReceiptResponse containing the processed receipt & its identifying string.
+	ImportEmailReceipt(context.Context, *ImportEmailReceiptRequest) (*ImportEmailReceiptResponse, error)
 	// AwardPoints receives an AwardPointsRequest containing a unique identifying string representing a processed receipt,
 	// and returns an AwardPointsResponse containing the associated points being awarded.
 	AwardPoints(context.Context, *AwardPointsRequest) (*AwardPointsResponse, error)
+	// GetBalance receives a GetBalanceRequest containing a user's identifying string,
+	// and returns a GetBalanceResponse containing the user's running total of awarded points.
+	GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error)
+	// ListLedgerEntries receives a ListLedgerEntriesRequest containing a user's identifying string,
+	// and returns a ListLedgerEntriesResponse containing every posting of points to the user, oldest first.
+	ListLedgerEntries(context.Context, *ListLedgerEntriesRequest) (*ListLedgerEntriesResponse, error)
+	// RedeemPoints receives a RedeemPointsRequest containing a user's identifying string & the points they are spending,
+	// and returns a RedeemPointsResponse containing the debit posted to the user's balance.
+	RedeemPoints(context.Context, *RedeemPointsRequest) (*RedeemPointsResponse, error)
+	// ReversePoints receives a ReversePointsRequest identifying an earlier award or redemption,
+	// and returns a ReversePointsResponse containing the entry posted to undo it.
+	ReversePoints(context.Context, *ReversePointsRequest) (*ReversePointsResponse, error)
+	// PreviewExpiringPoints receives a PreviewExpiringPointsRequest containing a user's identifying string and/or a set of receipts,
+	// and returns a PreviewExpiringPointsResponse containing the points lots which expire within the requested number of days.
+	PreviewExpiringPoints(context.Context, *PreviewExpiringPointsRequest) (*PreviewExpiringPointsResponse, error)
+	// ListFlaggedReceipts receives a ListFlaggedReceiptsRequest, optionally naming a fraud review outcome,
+	// and returns a ListFlaggedReceiptsResponse containing the receipts flagged, held or rejected as likely fraudulent, most recent first.
+	ListFlaggedReceipts(context.Context, *ListFlaggedReceiptsRequest) (*ListFlaggedReceiptsResponse, error)
+	// ReviewReceipt receives a ReviewReceiptRequest, naming a stored receipt & the review's decision,
+	// and returns a ReviewReceiptResponse once the receipt is released for awarding or rejected & removed, & no longer listed for review.
+	ReviewReceipt(context.Context, *ReviewReceiptRequest) (*ReviewReceiptResponse, error)
 	mustEmbedUnimplementedReceiptServiceServer()
 }
 
@@ -102,9 +238,33 @@ type UnimplementedReceiptServiceServer struct{}
 func (UnimplementedReceiptServiceServer) ProcessReceipt(context.Context, *ProcessReceiptRequest) (*ProcessReceiptResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ProcessReceipt not implemented")
 }
+func (UnimplementedReceiptServiceServer) ImportEmailReceipt(context.Context, *ImportEmailReceiptRequest) (*ImportEmailReceiptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportEmailReceipt not implemented")
+}
 func (UnimplementedReceiptServiceServer) AwardPoints(context.Context, *AwardPointsRequest) (*AwardPointsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AwardPoints not implemented")
 }
+func (UnimplementedReceiptServiceServer) GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedReceiptServiceServer) ListLedgerEntries(context.Context, *ListLedgerEntriesRequest) (*ListLedgerEntriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLedgerEntries not implemented")
+}
+func (UnimplementedReceiptServiceServer) RedeemPoints(context.Context, *RedeemPointsRequest) (*RedeemPointsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RedeemPoints not implemented")
+}
+func (UnimplementedReceiptServiceServer) ReversePoints(context.Context, *ReversePointsRequest) (*ReversePointsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReversePoints not implemented")
+}
+func (UnimplementedReceiptServiceServer) PreviewExpiringPoints(context.Context, *PreviewExpiringPointsRequest) (*PreviewExpiringPointsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PreviewExpiringPoints not implemented")
+}
+func (UnimplementedReceiptServiceServer) ListFlaggedReceipts(context.Context, *ListFlaggedReceiptsRequest) (*ListFlaggedReceiptsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFlaggedReceipts not implemented")
+}
+func (UnimplementedReceiptServiceServer) ReviewReceipt(context.Context, *ReviewReceiptRequest) (*ReviewReceiptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReviewReceipt not implemented")
+}
 func (UnimplementedReceiptServiceServer) mustEmbedUnimplementedReceiptServiceServer() {}
 func (UnimplementedReceiptServiceServer) testEmbeddedByValue()                        {}
 
@@ -144,6 +304,24 @@ func _ReceiptService_ProcessReceipt_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ReceiptService_ImportEmailReceipt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportEmailReceiptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceiptServiceServer).ImportEmailReceipt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceiptService_ImportEmailReceipt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceiptServiceServer).ImportEmailReceipt(ctx, req.(*ImportEmailReceiptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ReceiptService_AwardPoints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AwardPointsRequest)
 	if err := dec(in); err != nil {
@@ -162,6 +340,132 @@ func _ReceiptService_AwardPoints_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ReceiptService_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceiptServiceServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceiptService_GetBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceiptServiceServer).GetBalance(ctx, req.(*GetBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceiptService_ListLedgerEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLedgerEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceiptServiceServer).ListLedgerEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceiptService_ListLedgerEntries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceiptServiceServer).ListLedgerEntries(ctx, req.(*ListLedgerEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceiptService_RedeemPoints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedeemPointsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceiptServiceServer).RedeemPoints(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceiptService_RedeemPoints_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceiptServiceServer).RedeemPoints(ctx, req.(*RedeemPointsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceiptService_ReversePoints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReversePointsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceiptServiceServer).ReversePoints(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceiptService_ReversePoints_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceiptServiceServer).ReversePoints(ctx, req.(*ReversePointsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceiptService_PreviewExpiringPoints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PreviewExpiringPointsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceiptServiceServer).PreviewExpiringPoints(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceiptService_PreviewExpiringPoints_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceiptServiceServer).PreviewExpiringPoints(ctx, req.(*PreviewExpiringPointsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceiptService_ListFlaggedReceipts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFlaggedReceiptsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceiptServiceServer).ListFlaggedReceipts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceiptService_ListFlaggedReceipts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceiptServiceServer).ListFlaggedReceipts(ctx, req.(*ListFlaggedReceiptsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceiptService_ReviewReceipt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewReceiptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceiptServiceServer).ReviewReceipt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceiptService_ReviewReceipt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceiptServiceServer).ReviewReceipt(ctx, req.(*ReviewReceiptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ReceiptService_ServiceDesc is the grpc.ServiceDesc for ReceiptService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -173,10 +477,42 @@ var ReceiptService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ProcessReceipt",
 			Handler:    _ReceiptService_ProcessReceipt_Handler,
 		},
+		{
+			MethodName: "ImportEmailReceipt",
+			Handler:    _ReceiptService_ImportEmailReceipt_Handler,
+		},
 		{
 			MethodName: "AwardPoints",
 			Handler:    _ReceiptService_AwardPoints_Handler,
 		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _ReceiptService_GetBalance_Handler,
+		},
+		{
+			MethodName: "ListLedgerEntries",
+			Handler:    _ReceiptService_ListLedgerEntries_Handler,
+		},
+		{
+			MethodName: "RedeemPoints",
+			Handler:    _ReceiptService_RedeemPoints_Handler,
+		},
+		{
+			MethodName: "ReversePoints",
+			Handler:    _ReceiptService_ReversePoints_Handler,
+		},
+		{
+			MethodName: "PreviewExpiringPoints",
+			Handler:    _ReceiptService_PreviewExpiringPoints_Handler,
+		},
+		{
+			MethodName: "ListFlaggedReceipts",
+			Handler:    _ReceiptService_ListFlaggedReceipts_Handler,
+		},
+		{
+			MethodName: "ReviewReceipt",
+			Handler:    _ReceiptService_ReviewReceipt_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "service.proto",
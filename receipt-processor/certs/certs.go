@@ -0,0 +1,149 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+
+	reload "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/reload"
+)
+
+// A Keypair serves a certificate & private key read from PEM files, reloaded when either changes,
+// so a renewed certificate is presented on new connections without a restart.
+type Keypair struct {
+	certFile, keyFile string
+	cert              *tls.Certificate
+	stamps            []reload.Stamp
+	sync.RWMutex
+}
+
+// LoadKeypair reads a PEM certificate (chain) & its private key.
+func LoadKeypair(certFile string, keyFile string) (kp *Keypair, err error) {
+	kp = &Keypair{certFile: certFile, keyFile: keyFile}
+	if _, err := kp.Reload(); err != nil {
+		return nil, err
+	}
+	return kp, nil
+}
+
+func (kp *Keypair) Reload() (changed bool, err error) {
+	stamps, err := reload.StampFiles(kp.certFile, kp.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("error reading certificate: %w", err)
+	}
+	kp.RLock()
+	unchanged := reload.Unchanged(stamps, kp.stamps)
+	kp.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(kp.certFile, kp.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("error loading certificate %s: %w", kp.certFile, err)
+	}
+	kp.Lock()
+	defer kp.Unlock()
+	kp.cert, kp.stamps = &cert, stamps
+	return true, nil
+}
+
+// Certificate returns the certificate currently being served.
+func (kp *Keypair) Certificate() *tls.Certificate {
+	kp.RLock()
+	defer kp.RUnlock()
+	return kp.cert
+}
+
+func (kp *Keypair) String() string { return kp.certFile }
+
+// A Pool serves a pool of CA certificates read from a PEM file, reloaded when it changes.
+type Pool struct {
+	file   string
+	pool   *x509.CertPool
+	stamps []reload.Stamp
+	sync.RWMutex
+}
+
+// LoadPool reads a PEM file of one or more CA certificates.
+func LoadPool(file string) (p *Pool, err error) {
+	p = &Pool{file: file}
+	if _, err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Pool) Reload() (changed bool, err error) {
+	stamps, err := reload.StampFiles(p.file)
+	if err != nil {
+		return false, fmt.Errorf("error reading CA certificates: %w", err)
+	}
+	p.RLock()
+	unchanged := reload.Unchanged(stamps, p.stamps)
+	p.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	pem, err := os.ReadFile(p.file)
+	if err != nil {
+		return false, fmt.Errorf("error reading CA certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return false, fmt.Errorf("no CA certificates found in %s", p.file)
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.pool, p.stamps = pool, stamps
+	return true, nil
+}
+
+// CertPool returns the CA certificates currently trusted.
+func (p *Pool) CertPool() *x509.CertPool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.pool
+}
+
+func (p *Pool) String() string { return p.file }
+
+// ServerConfig returns a TLS configuration presenting a keypair, which verifies client certificates
+// against clientCAs when given: requiring one when requireClientCert is set, or else only checking those presented.
+// Each handshake uses the keypair & CAs as they are at the time.
+func ServerConfig(kp *Keypair, clientCAs *Pool, requireClientCert bool) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			// the config returned replaces the one gRPC & net/http add their protocols to, so offer both here
+			c := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{*kp.Certificate()}, NextProtos: []string{"h2", "http/1.1"}}
+			if clientCAs != nil {
+				c.ClientCAs = clientCAs.CertPool()
+				if requireClientCert {
+					c.ClientAuth = tls.RequireAndVerifyClientCert
+				} else {
+					c.ClientAuth = tls.VerifyClientCertIfGiven
+				}
+			}
+			return c, nil
+		},
+	}
+}
+
+// ClientConfig returns a TLS configuration verifying servers against rootCAs, or the system's roots when nil,
+// as serverName. When a keypair is given, it's presented to servers which request a client certificate.
+func ClientConfig(kp *Keypair, rootCAs *Pool, serverName string) *tls.Config {
+	c := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: serverName}
+	if rootCAs != nil {
+		c.RootCAs = rootCAs.CertPool()
+	}
+	if kp != nil {
+		c.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return kp.Certificate(), nil
+		}
+	}
+	return c
+}
@@ -0,0 +1,254 @@
+package certs_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/certs"
+)
+
+// authority is a locally generated test CA.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string // the CA certificate, as PEM
+}
+
+// serial numbers each certificate issued, & modified each file written
+var serial, modified int64
+
+// newAuthority generates a self-signed CA, writing its certificate to dir.
+func newAuthority(t *testing.T, dir string, name string) *authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unexpected error generating CA key: %v", err)
+	}
+	serial++
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unexpected error creating CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	ca := &authority{cert: cert, key: key, file: filepath.Join(dir, name+".pem")}
+	writePEM(t, ca.file, "CERTIFICATE", der)
+	return ca
+}
+
+// issue signs a certificate for localhost, usable by servers & clients, writing it & its key to the given files.
+func (ca *authority) issue(t *testing.T, certFile string, keyFile string) (serialNumber int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unexpected error generating key: %v", err)
+	}
+	serial++
+	serialNumber = serial
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serialNumber),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Unexpected error creating certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return serialNumber
+}
+
+// writePEM writes a PEM block, marking the file as modified later than any previous version.
+func writePEM(t *testing.T, file string, kind string, der []byte) {
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Unexpected error writing %s: %v", file, err)
+	}
+	modified++
+	later := time.Now().Add(time.Duration(modified) * time.Second)
+	os.Chtimes(file, later, later)
+}
+
+// handshake connects a client to a server, returning the serial number of the server's certificate.
+func handshake(t *testing.T, server *tls.Config, client *tls.Config) (serialNumber int64, err error) {
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	if err != nil {
+		t.Fatalf("Unexpected error listening: %v", err)
+	}
+	defer lis.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", lis.Addr().String(), client)
+	if err != nil {
+		<-serverErr
+		return 0, err
+	}
+	defer conn.Close()
+	if err := <-serverErr; err != nil {
+		return 0, err
+	}
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+func TestServerConfig_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCA := newAuthority(t, dir, "server-ca")
+	clientCA := newAuthority(t, dir, "client-ca")
+	otherCA := newAuthority(t, dir, "other-ca")
+	serverCA.issue(t, filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem"))
+	clientCA.issue(t, filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem"))
+	otherCA.issue(t, filepath.Join(dir, "other.pem"), filepath.Join(dir, "other-key.pem"))
+
+	load := func(name string) *certs.Keypair {
+		kp, err := certs.LoadKeypair(filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem"))
+		if err != nil {
+			t.Fatalf("Unexpected error loading keypair %s: %v", name, err)
+		}
+		return kp
+	}
+	serverCert, clientCert, otherCert := load("server"), load("client"), load("other")
+	roots, _ := certs.LoadPool(serverCA.file)
+	clientCAs, _ := certs.LoadPool(clientCA.file)
+
+	type testCase struct {
+		clientCAs   *certs.Pool
+		require     bool
+		clientCert  *certs.Keypair
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		// plain TLS
+		{clientCAs: nil, clientCert: nil},
+		{clientCAs: nil, clientCert: clientCert},
+		// client certificates verified if given
+		{clientCAs: clientCAs, require: false, clientCert: nil},
+		{clientCAs: clientCAs, require: false, clientCert: clientCert},
+		{clientCAs: clientCAs, require: false, clientCert: otherCert, errExpected: true},
+		// client certificates required
+		{clientCAs: clientCAs, require: true, clientCert: clientCert},
+		{clientCAs: clientCAs, require: true, clientCert: nil, errExpected: true},
+		{clientCAs: clientCAs, require: true, clientCert: otherCert, errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		server := certs.ServerConfig(serverCert, tc.clientCAs, tc.require)
+		client := certs.ClientConfig(tc.clientCert, roots, "localhost")
+		if _, err := handshake(t, server, client); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error during handshake in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+
+	// servers are verified too
+	if _, err := handshake(t, certs.ServerConfig(otherCert, nil, false), certs.ClientConfig(nil, roots, "localhost")); err == nil {
+		t.Errorf("Expected a server certificate from an untrusted CA to be rejected")
+	}
+}
+
+func TestKeypair_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, dir, "ca")
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")
+	first := ca.issue(t, certFile, keyFile)
+
+	kp, err := certs.LoadKeypair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Unexpected error loading keypair: %v", err)
+	}
+	roots, _ := certs.LoadPool(ca.file)
+	server, client := certs.ServerConfig(kp, nil, false), certs.ClientConfig(nil, roots, "localhost")
+
+	if changed, err := kp.Reload(); changed || err != nil {
+		t.Errorf("Expected no reload of unchanged files, received %t & %v", changed, err)
+	}
+	if got, _ := handshake(t, server, client); got != first {
+		t.Errorf("Expected the first certificate %d to be served, received %d", first, got)
+	}
+
+	// a renewed certificate is served once reloaded, by the same server configuration
+	second := ca.issue(t, certFile, keyFile)
+	if changed, err := kp.Reload(); !changed || err != nil {
+		t.Errorf("Expected the renewed certificate to be reloaded, received %t & %v", changed, err)
+	}
+	if got, _ := handshake(t, server, client); got != second {
+		t.Errorf("Expected the renewed certificate %d to be served, received %d", second, got)
+	}
+
+	// a broken renewal keeps the last good certificate
+	writePEM(t, certFile, "CERTIFICATE", []byte("not a certificate"))
+	if _, err := kp.Reload(); err == nil {
+		t.Errorf("Expected an error reloading an invalid certificate")
+	}
+	if got, _ := handshake(t, server, client); got != second {
+		t.Errorf("Expected the renewed certificate %d to still be served, received %d", second, got)
+	}
+}
+
+func TestPool_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, dir, "ca")
+	ca.issue(t, filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem"))
+	serverCert, _ := certs.LoadKeypair(filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem"))
+	roots, _ := certs.LoadPool(ca.file)
+
+	// clients of a new CA are only trusted once the client CA file is updated to include it
+	clientCAFile := filepath.Join(dir, "client-cas.pem")
+	oldCA := newAuthority(t, dir, "old-ca")
+	newCA := newAuthority(t, dir, "new-ca")
+	oldPEM, _ := os.ReadFile(oldCA.file)
+	newPEM, _ := os.ReadFile(newCA.file)
+	os.WriteFile(clientCAFile, oldPEM, 0o600)
+	clientCAs, err := certs.LoadPool(clientCAFile)
+	if err != nil {
+		t.Fatalf("Unexpected error loading client CAs: %v", err)
+	}
+
+	newCA.issue(t, filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem"))
+	clientCert, _ := certs.LoadKeypair(filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem"))
+	server, client := certs.ServerConfig(serverCert, clientCAs, true), certs.ClientConfig(clientCert, roots, "localhost")
+
+	if _, err := handshake(t, server, client); err == nil {
+		t.Errorf("Expected a client of an untrusted CA to be rejected")
+	}
+	os.WriteFile(clientCAFile, append(oldPEM, newPEM...), 0o600)
+	if changed, err := clientCAs.Reload(); !changed || err != nil {
+		t.Errorf("Expected the client CAs to be reloaded, received %t & %v", changed, err)
+	}
+	if _, err := handshake(t, server, client); err != nil {
+		t.Errorf("Unexpected error during handshake after reloading client CAs: %v", err)
+	}
+}
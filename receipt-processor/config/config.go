@@ -0,0 +1,539 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	logging "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/logging"
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// Config is the effective configuration of the Receipt Processor, layered from its defaults,
+// a JSON config file, RECEIPT_* environment variables & command-line flags, each overriding the last.
+type Config struct {
+	GRPCAddr   string     `json:"grpcAddr"` // Address the gRPC server listens on.
+	HTTPAddr   string     `json:"httpAddr"` // Address the HTTP gateway listens on.
+	Timeouts   Timeouts   `json:"timeouts"`
+	TLS        TLS        `json:"tls"`
+	Auth       Auth       `json:"auth"`
+	Limits     Limits     `json:"limits"`
+	Validation Validation `json:"validation"`
+	Formats    Formats    `json:"formats"`
+	Tracing    Tracing    `json:"tracing"`
+	Store      string     `json:"store"`     // The receipt store backend; only "memory" is supported.
+	LogLevel   string     `json:"logLevel"`  // debug, info, warn or error.
+	LogFormat  string     `json:"logFormat"` // text or json.
+}
+
+// Timeouts bound how long the gateway & servers wait on connections.
+type Timeouts struct {
+	Read     Duration `json:"read"`     // Most time spent reading an HTTP request, including its body.
+	Write    Duration `json:"write"`    // Most time spent writing an HTTP response.
+	Idle     Duration `json:"idle"`     // Most time an idle keep-alive HTTP connection is kept open.
+	Shutdown Duration `json:"shutdown"` // Most time in-flight requests are given to finish on shutdown.
+}
+
+// TLS secures both listeners with a certificate, & optionally verifies the client certificates of gRPC callers (mTLS).
+// Certificate & CA files are reloaded when they change, so renewals don't need a restart.
+type TLS struct {
+	CertFile          string   `json:"certFile"`          // PEM certificate (chain) both listeners present; TLS is off without one.
+	KeyFile           string   `json:"keyFile"`           // PEM private key of the certificate.
+	CAFile            string   `json:"caFile"`            // Optional. CAs the gateway verifies the gRPC server by, instead of the system's.
+	ClientCAFile      string   `json:"clientCAFile"`      // Optional. CAs gRPC client certificates are verified against.
+	RequireClientCert bool     `json:"requireClientCert"` // Whether gRPC callers must present a client certificate.
+	ServerName        string   `json:"serverName"`        // Optional. Name the gateway expects on the gRPC server's certificate.
+	ReloadInterval    Duration `json:"reloadInterval"`    // How often certificate files are checked for changes; never when zero.
+}
+
+// Enabled reports whether the listeners are secured with TLS.
+func (t *TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// Auth requires callers to authenticate, when a keys file or JWKS file is given.
+type Auth struct {
+	APIKeysFile    string   `json:"apiKeysFile"`    // JSON array of hashed API keys & their scopes; callers aren't authenticated without one.
+	JWKSFile       string   `json:"jwksFile"`       // JSON Web Key Set bearer tokens are verified against; none are accepted without one.
+	Issuer         string   `json:"issuer"`         // Issuer bearer tokens must claim.
+	Audience       string   `json:"audience"`       // Audience bearer tokens must claim.
+	Leeway         Duration `json:"leeway"`         // Clock skew tolerated when checking bearer tokens' expiry.
+	ReloadInterval Duration `json:"reloadInterval"` // How often the keys & JWKS files are checked for changes; never when zero.
+}
+
+// Enabled reports whether callers must authenticate.
+func (a *Auth) Enabled() bool {
+	return a.APIKeysFile != "" || a.JWKSFile != ""
+}
+
+// Limits holds each client to the rate limits & daily quota in a policy file, when one is given.
+type Limits struct {
+	File           string   `json:"file"`           // JSON rate limit policy; clients aren't limited without one, or when it's "none".
+	ReloadInterval Duration `json:"reloadInterval"` // How often the policy file is checked for changes; never when zero.
+}
+
+// Enabled reports whether clients are rate limited. Limits set by a config file are turned off by "none",
+// since empty environment variables & flags leave settings as they were.
+func (l *Limits) Enabled() bool {
+	return l.File != "" && l.File != "none"
+}
+
+// Validation bounds the receipts accepted for processing, beyond each field's format. Zero limits aren't enforced.
+type Validation struct {
+	MaxItems             int      `json:"maxItems"`             // Most items on a single receipt.
+	MaxDescriptionLength int      `json:"maxDescriptionLength"` // Most characters in an item's short description.
+	MaxTotal             float64  `json:"maxTotal"`             // Largest total, in the base currency.
+	MaxAge               Duration `json:"maxAge"`               // Furthest a purchase may be in the past.
+	MaxFuture            Duration `json:"maxFuture"`            // Furthest a purchase may be in the future, allowing for clock skew.
+	RequiredFields       []string `json:"requiredFields"`       // Receipt fields which may not be empty, by their JSON name.
+}
+
+// Policy returns the validation policy receipts are processed under.
+func (v *Validation) Policy() model.ValidationPolicy {
+	return model.ValidationPolicy{
+		MaxItems:             v.MaxItems,
+		MaxDescriptionLength: v.MaxDescriptionLength,
+		MaxTotal:             v.MaxTotal,
+		MaxAge:               time.Duration(v.MaxAge),
+		MaxFuture:            time.Duration(v.MaxFuture),
+		RequiredFields:       slices.Clone(v.RequiredFields),
+	}
+}
+
+// Formats are the Go time layouts purchase dates & times are recognized in, before they're normalized.
+// Layouts may contain commas, so they're only set in a config file.
+type Formats struct {
+	DateLayouts []string `json:"dateLayouts"` // e.g. "1/2/2006"; a date several layouts read as different days is rejected.
+	TimeLayouts []string `json:"timeLayouts"` // e.g. "3:04 PM"; meridiem markers are matched in upper case.
+}
+
+// Tracing exports OpenTelemetry spans of each request, for local debugging.
+type Tracing struct {
+	Exporter    string  `json:"exporter"`    // none, stdout or file.
+	File        string  `json:"file"`        // File spans are appended to, one JSON object per line, by the file exporter.
+	SampleRatio float64 `json:"sampleRatio"` // Share of traces started here which are sampled, from 0 to 1; callers' sampling decisions are kept.
+}
+
+// Enabled reports whether spans are exported.
+func (t *Tracing) Enabled() bool {
+	return t.Exporter != "none"
+}
+
+// A Duration is a time.Duration written as a string like "5s" or "1m30s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("durations must be strings like \"5s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	*d = Duration(parsed)
+	return err
+}
+
+// Stores are the receipt store backends available.
+var Stores = []string{"memory"}
+
+// TraceExporters are the span exporters available.
+var TraceExporters = []string{"none", "stdout", "file"}
+
+// LogLevels are the log levels available, most verbose first.
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// Default returns the configuration used where nothing else is configured.
+func Default() Config {
+	return Config{
+		GRPCAddr: ":80",
+		HTTPAddr: ":8081",
+		Timeouts: Timeouts{
+			Read:     Duration(10 * time.Second),
+			Write:    Duration(30 * time.Second),
+			Idle:     Duration(2 * time.Minute),
+			Shutdown: Duration(30 * time.Second),
+		},
+		TLS:    TLS{ReloadInterval: Duration(time.Minute)},
+		Auth:   Auth{ReloadInterval: Duration(time.Minute)},
+		Limits: Limits{ReloadInterval: Duration(time.Minute)},
+		Validation: Validation{
+			MaxItems:             model.DefaultValidationPolicy.MaxItems,
+			MaxDescriptionLength: model.DefaultValidationPolicy.MaxDescriptionLength,
+			MaxTotal:             model.DefaultValidationPolicy.MaxTotal,
+			MaxAge:               Duration(model.DefaultValidationPolicy.MaxAge),
+			MaxFuture:            Duration(model.DefaultValidationPolicy.MaxFuture),
+			RequiredFields:       slices.Clone(model.DefaultValidationPolicy.RequiredFields),
+		},
+		Formats: Formats{
+			DateLayouts: slices.Clone(model.DefaultDateLayouts),
+			TimeLayouts: slices.Clone(model.DefaultTimeLayouts),
+		},
+		Tracing:   Tracing{Exporter: "none", SampleRatio: 1},
+		Store:     "memory",
+		LogLevel:  "info",
+		LogFormat: "text",
+	}
+}
+
+// ENV_PREFIX prefixes the environment variable of each setting, e.g. RECEIPT_GRPC_ADDR.
+const ENV_PREFIX = "RECEIPT_"
+
+// Load returns the configuration given by the command-line arguments (without the program name) & environment.
+// The config file is named by the -config flag or RECEIPT_CONFIG; printConfig is set by -print-config.
+func Load(args []string, getenv func(string) string) (c Config, printConfig bool, err error) {
+	c = Default()
+
+	fs := flag.NewFlagSet("receipt-processor", flag.ContinueOnError)
+	path := fs.String("config", getenv(ENV_PREFIX+"CONFIG"), "path to a JSON config file")
+	fs.BoolVar(&printConfig, "print-config", false, "print the effective configuration as JSON, then exit")
+	// flags are parsed into their own config, so only those set override the file & environment
+	var flags Config
+	fs.StringVar(&flags.GRPCAddr, "grpc-addr", "", "address the gRPC server listens on")
+	fs.StringVar(&flags.HTTPAddr, "http-addr", "", "address the HTTP gateway listens on")
+	fs.Func("read-timeout", "most time spent reading an HTTP request", durationFlag(&flags.Timeouts.Read))
+	fs.Func("write-timeout", "most time spent writing an HTTP response", durationFlag(&flags.Timeouts.Write))
+	fs.Func("idle-timeout", "most time an idle HTTP connection is kept open", durationFlag(&flags.Timeouts.Idle))
+	fs.Func("shutdown-timeout", "most time in-flight requests are given on shutdown", durationFlag(&flags.Timeouts.Shutdown))
+	fs.StringVar(&flags.TLS.CertFile, "tls-cert", "", "PEM certificate both listeners present")
+	fs.StringVar(&flags.TLS.KeyFile, "tls-key", "", "PEM private key of the certificate")
+	fs.StringVar(&flags.TLS.CAFile, "tls-ca", "", "CAs the gateway verifies the gRPC server by")
+	fs.StringVar(&flags.TLS.ClientCAFile, "tls-client-ca", "", "CAs gRPC client certificates are verified against")
+	fs.BoolVar(&flags.TLS.RequireClientCert, "tls-require-client-cert", false, "require gRPC callers to present a client certificate")
+	fs.StringVar(&flags.TLS.ServerName, "tls-server-name", "", "name the gateway expects on the gRPC server's certificate")
+	fs.Func("tls-reload-interval", "how often certificate files are checked for changes", durationFlag(&flags.TLS.ReloadInterval))
+	fs.StringVar(&flags.Auth.APIKeysFile, "api-keys", "", "JSON file of hashed API keys callers must present")
+	fs.StringVar(&flags.Auth.JWKSFile, "jwks", "", "JSON Web Key Set file bearer tokens are verified against")
+	fs.StringVar(&flags.Auth.Issuer, "jwt-issuer", "", "issuer bearer tokens must claim")
+	fs.StringVar(&flags.Auth.Audience, "jwt-audience", "", "audience bearer tokens must claim")
+	fs.Func("jwt-leeway", "clock skew tolerated when checking bearer tokens' expiry", durationFlag(&flags.Auth.Leeway))
+	fs.Func("auth-reload-interval", "how often the API keys & JWKS files are checked for changes", durationFlag(&flags.Auth.ReloadInterval))
+	fs.StringVar(&flags.Limits.File, "limits", "", "JSON rate limit policy file, e.g. receipt-processor/data/limits.json; clients aren't limited when empty or none")
+	fs.Func("limits-reload-interval", "how often the rate limit policy file is checked for changes", durationFlag(&flags.Limits.ReloadInterval))
+	fs.IntVar(&flags.Validation.MaxItems, "max-items", 0, "most items on a single receipt; unlimited when zero")
+	fs.IntVar(&flags.Validation.MaxDescriptionLength, "max-description-length", 0, "most characters in an item's short description; unlimited when zero")
+	fs.Float64Var(&flags.Validation.MaxTotal, "max-total", 0, "largest receipt total, in the base currency; unlimited when zero")
+	fs.Func("max-age", "furthest a purchase may be in the past; unlimited when zero", durationFlag(&flags.Validation.MaxAge))
+	fs.Func("max-future", "furthest a purchase may be in the future; unlimited when zero", durationFlag(&flags.Validation.MaxFuture))
+	fs.Func("required-fields", "comma-separated receipt fields which may not be empty, of "+strings.Join(model.Fields, ", "), listFlag(&flags.Validation.RequiredFields))
+	fs.StringVar(&flags.Tracing.Exporter, "trace-exporter", "", "span exporter: "+strings.Join(TraceExporters, ", "))
+	fs.StringVar(&flags.Tracing.File, "trace-file", "", "file the file exporter appends spans to")
+	fs.Float64Var(&flags.Tracing.SampleRatio, "trace-sample-ratio", 0, "share of new traces sampled, from 0 to 1")
+	fs.StringVar(&flags.Store, "store", "", "receipt store backend: "+strings.Join(Stores, ", "))
+	fs.StringVar(&flags.LogLevel, "log-level", "", "log level: "+strings.Join(LogLevels, ", "))
+	fs.StringVar(&flags.LogFormat, "log-format", "", "log format: "+strings.Join(logging.FORMATS, ", "))
+	if err := fs.Parse(args); err != nil {
+		return Config{}, false, err
+	} else if fs.NArg() > 0 {
+		return Config{}, false, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	if *path != "" {
+		if err := c.loadFile(*path); err != nil {
+			return Config{}, false, err
+		}
+	}
+	if err := c.loadEnv(getenv); err != nil {
+		return Config{}, false, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "grpc-addr":
+			c.GRPCAddr = flags.GRPCAddr
+		case "http-addr":
+			c.HTTPAddr = flags.HTTPAddr
+		case "read-timeout":
+			c.Timeouts.Read = flags.Timeouts.Read
+		case "write-timeout":
+			c.Timeouts.Write = flags.Timeouts.Write
+		case "idle-timeout":
+			c.Timeouts.Idle = flags.Timeouts.Idle
+		case "shutdown-timeout":
+			c.Timeouts.Shutdown = flags.Timeouts.Shutdown
+		case "tls-cert":
+			c.TLS.CertFile = flags.TLS.CertFile
+		case "tls-key":
+			c.TLS.KeyFile = flags.TLS.KeyFile
+		case "tls-ca":
+			c.TLS.CAFile = flags.TLS.CAFile
+		case "tls-client-ca":
+			c.TLS.ClientCAFile = flags.TLS.ClientCAFile
+		case "tls-require-client-cert":
+			c.TLS.RequireClientCert = flags.TLS.RequireClientCert
+		case "tls-server-name":
+			c.TLS.ServerName = flags.TLS.ServerName
+		case "tls-reload-interval":
+			c.TLS.ReloadInterval = flags.TLS.ReloadInterval
+		case "api-keys":
+			c.Auth.APIKeysFile = flags.Auth.APIKeysFile
+		case "jwks":
+			c.Auth.JWKSFile = flags.Auth.JWKSFile
+		case "jwt-issuer":
+			c.Auth.Issuer = flags.Auth.Issuer
+		case "jwt-audience":
+			c.Auth.Audience = flags.Auth.Audience
+		case "jwt-leeway":
+			c.Auth.Leeway = flags.Auth.Leeway
+		case "auth-reload-interval":
+			c.Auth.ReloadInterval = flags.Auth.ReloadInterval
+		case "limits":
+			c.Limits.File = flags.Limits.File
+		case "limits-reload-interval":
+			c.Limits.ReloadInterval = flags.Limits.ReloadInterval
+		case "max-items":
+			c.Validation.MaxItems = flags.Validation.MaxItems
+		case "max-description-length":
+			c.Validation.MaxDescriptionLength = flags.Validation.MaxDescriptionLength
+		case "max-total":
+			c.Validation.MaxTotal = flags.Validation.MaxTotal
+		case "max-age":
+			c.Validation.MaxAge = flags.Validation.MaxAge
+		case "max-future":
+			c.Validation.MaxFuture = flags.Validation.MaxFuture
+		case "required-fields":
+			c.Validation.RequiredFields = flags.Validation.RequiredFields
+		case "trace-exporter":
+			c.Tracing.Exporter = flags.Tracing.Exporter
+		case "trace-file":
+			c.Tracing.File = flags.Tracing.File
+		case "trace-sample-ratio":
+			c.Tracing.SampleRatio = flags.Tracing.SampleRatio
+		case "store":
+			c.Store = flags.Store
+		case "log-level":
+			c.LogLevel = flags.LogLevel
+		case "log-format":
+			c.LogFormat = flags.LogFormat
+		}
+	})
+
+	if err := c.Validate(); err != nil {
+		return Config{}, false, err
+	}
+	return c, printConfig, nil
+}
+
+// loadFile overrides the settings present in a JSON config file.
+func (c *Config) loadFile(path string) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening config file: %w", err)
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("error decoding config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides the settings given by non-empty environment variables.
+func (c *Config) loadEnv(getenv func(string) string) (err error) {
+	strs := map[string]*string{
+		"GRPC_ADDR":          &c.GRPCAddr,
+		"HTTP_ADDR":          &c.HTTPAddr,
+		"TLS_CERT_FILE":      &c.TLS.CertFile,
+		"TLS_KEY_FILE":       &c.TLS.KeyFile,
+		"TLS_CA_FILE":        &c.TLS.CAFile,
+		"TLS_CLIENT_CA_FILE": &c.TLS.ClientCAFile,
+		"TLS_SERVER_NAME":    &c.TLS.ServerName,
+		"API_KEYS_FILE":      &c.Auth.APIKeysFile,
+		"JWKS_FILE":          &c.Auth.JWKSFile,
+		"JWT_ISSUER":         &c.Auth.Issuer,
+		"JWT_AUDIENCE":       &c.Auth.Audience,
+		"LIMITS_FILE":        &c.Limits.File,
+		"TRACE_EXPORTER":     &c.Tracing.Exporter,
+		"TRACE_FILE":         &c.Tracing.File,
+		"STORE":              &c.Store,
+		"LOG_LEVEL":          &c.LogLevel,
+		"LOG_FORMAT":         &c.LogFormat,
+	}
+	for name, setting := range strs {
+		if v := getenv(ENV_PREFIX + name); v != "" {
+			*setting = v
+		}
+	}
+
+	durations := map[string]*Duration{
+		"READ_TIMEOUT":           &c.Timeouts.Read,
+		"WRITE_TIMEOUT":          &c.Timeouts.Write,
+		"IDLE_TIMEOUT":           &c.Timeouts.Idle,
+		"SHUTDOWN_TIMEOUT":       &c.Timeouts.Shutdown,
+		"TLS_RELOAD_INTERVAL":    &c.TLS.ReloadInterval,
+		"AUTH_RELOAD_INTERVAL":   &c.Auth.ReloadInterval,
+		"JWT_LEEWAY":             &c.Auth.Leeway,
+		"LIMITS_RELOAD_INTERVAL": &c.Limits.ReloadInterval,
+		"MAX_AGE":                &c.Validation.MaxAge,
+		"MAX_FUTURE":             &c.Validation.MaxFuture,
+	}
+	for name, setting := range durations {
+		if v := getenv(ENV_PREFIX + name); v == "" {
+			continue
+		} else if d, err := time.ParseDuration(v); err != nil {
+			return fmt.Errorf("invalid %s%s: %w", ENV_PREFIX, name, err)
+		} else {
+			*setting = Duration(d)
+		}
+	}
+
+	if v := getenv(ENV_PREFIX + "TLS_REQUIRE_CLIENT_CERT"); v != "" {
+		if c.TLS.RequireClientCert, err = strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("invalid %sTLS_REQUIRE_CLIENT_CERT: %w", ENV_PREFIX, err)
+		}
+	}
+	ints := map[string]*int{
+		"MAX_ITEMS":              &c.Validation.MaxItems,
+		"MAX_DESCRIPTION_LENGTH": &c.Validation.MaxDescriptionLength,
+	}
+	for name, setting := range ints {
+		if v := getenv(ENV_PREFIX + name); v == "" {
+			continue
+		} else if *setting, err = strconv.Atoi(v); err != nil {
+			return fmt.Errorf("invalid %s%s: %w", ENV_PREFIX, name, err)
+		}
+	}
+	if v := getenv(ENV_PREFIX + "MAX_TOTAL"); v != "" {
+		if c.Validation.MaxTotal, err = strconv.ParseFloat(v, 64); err != nil {
+			return fmt.Errorf("invalid %sMAX_TOTAL: %w", ENV_PREFIX, err)
+		}
+	}
+	if v := getenv(ENV_PREFIX + "REQUIRED_FIELDS"); v != "" {
+		c.Validation.RequiredFields = splitList(v)
+	}
+	if v := getenv(ENV_PREFIX + "TRACE_SAMPLE_RATIO"); v != "" {
+		if c.Tracing.SampleRatio, err = strconv.ParseFloat(v, 64); err != nil {
+			return fmt.Errorf("invalid %sTRACE_SAMPLE_RATIO: %w", ENV_PREFIX, err)
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid setting in the configuration.
+func (c *Config) Validate() (err error) {
+	problems := make([]string, 0)
+	for name, addr := range map[string]string{"grpcAddr": c.GRPCAddr, "httpAddr": c.HTTPAddr} {
+		if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+			problems = append(problems, fmt.Sprintf("%s %q is not a host:port address", name, addr))
+		}
+	}
+	if c.GRPCAddr == c.HTTPAddr {
+		problems = append(problems, "grpcAddr & httpAddr must differ")
+	}
+	for name, d := range map[string]Duration{"read": c.Timeouts.Read, "write": c.Timeouts.Write, "idle": c.Timeouts.Idle, "shutdown": c.Timeouts.Shutdown} {
+		if d < 0 {
+			problems = append(problems, fmt.Sprintf("timeouts.%s may not be negative", name))
+		}
+	}
+	if c.TLS.ReloadInterval < 0 {
+		problems = append(problems, "tls.reloadInterval may not be negative")
+	}
+	if c.Auth.ReloadInterval < 0 {
+		problems = append(problems, "auth.reloadInterval may not be negative")
+	}
+	if c.Limits.ReloadInterval < 0 {
+		problems = append(problems, "limits.reloadInterval may not be negative")
+	}
+	if c.Auth.Leeway < 0 {
+		problems = append(problems, "auth.leeway may not be negative")
+	}
+	if c.Auth.JWKSFile != "" && (c.Auth.Issuer == "" || c.Auth.Audience == "") {
+		problems = append(problems, "auth.jwksFile needs an auth.issuer & auth.audience")
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		problems = append(problems, "tls.certFile & tls.keyFile must be given together")
+	}
+	if !c.TLS.Enabled() && (c.TLS.CAFile != "" || c.TLS.ClientCAFile != "" || c.TLS.ServerName != "") {
+		problems = append(problems, "tls.caFile, tls.clientCAFile & tls.serverName need a tls.certFile")
+	}
+	if c.TLS.RequireClientCert && c.TLS.ClientCAFile == "" {
+		problems = append(problems, "tls.requireClientCert needs a tls.clientCAFile")
+	}
+	policy := c.Validation.Policy()
+	if err := policy.Validate(); err != nil {
+		problems = append(problems, "validation: "+err.Error())
+	}
+	if len(c.Formats.DateLayouts) == 0 || len(c.Formats.TimeLayouts) == 0 {
+		problems = append(problems, "formats.dateLayouts & formats.timeLayouts may not be empty")
+	}
+	if !slices.Contains(TraceExporters, c.Tracing.Exporter) {
+		problems = append(problems, fmt.Sprintf("tracing.exporter %q is not one of %v", c.Tracing.Exporter, TraceExporters))
+	} else if (c.Tracing.Exporter == "file") != (c.Tracing.File != "") {
+		problems = append(problems, "tracing.file must be given with, & only with, the file exporter")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sampleRatio must be from 0 to 1")
+	}
+	if !slices.Contains(Stores, c.Store) {
+		problems = append(problems, fmt.Sprintf("store %q is not one of %v", c.Store, Stores))
+	}
+	if !slices.Contains(LogLevels, c.LogLevel) {
+		problems = append(problems, fmt.Sprintf("logLevel %q is not one of %v", c.LogLevel, LogLevels))
+	}
+	if !slices.Contains(logging.FORMATS, c.LogFormat) {
+		problems = append(problems, fmt.Sprintf("logFormat %q is not one of %v", c.LogFormat, logging.FORMATS))
+	}
+
+	if len(problems) > 0 {
+		// map iteration is unordered, so sort for stable messages
+		slices.Sort(problems)
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Print writes the configuration as indented JSON, in the format config files are read in.
+func (c *Config) Print(w io.Writer) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(c)
+}
+
+// DialAddr returns the address to reach a server listening on addr from the same host,
+// as wildcard listen addresses like ":80" or "0.0.0.0:80" can't be dialed everywhere.
+func DialAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	} else if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// durationFlag parses a flag's value as a Duration.
+func durationFlag(d *Duration) func(string) error {
+	return func(s string) (err error) {
+		parsed, err := time.ParseDuration(s)
+		*d = Duration(parsed)
+		return err
+	}
+}
+
+// listFlag sets a list flag from comma-separated values.
+func listFlag(l *[]string) func(string) error {
+	return func(s string) (err error) {
+		*l = splitList(s)
+		return nil
+	}
+}
+
+// splitList splits comma-separated values, trimming the space around each.
+func splitList(s string) (l []string) {
+	l = make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
@@ -0,0 +1,204 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/config"
+)
+
+// env is a fake environment.
+type env map[string]string
+
+func (e env) Getenv(name string) string { return e[name] }
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"grpcAddr": ":9090", "httpAddr": "127.0.0.1:9091", "timeouts": {"read": "5s"}, "logLevel": "debug"}`), 0o600); err != nil {
+		t.Fatalf("Unexpected error writing config file: %v", err)
+	}
+
+	formats := filepath.Join(t.TempDir(), "formats.json")
+	if err := os.WriteFile(formats, []byte(`{"formats": {"dateLayouts": ["2.1.2006", "Jan 2, 2006"], "timeLayouts": ["15:04"]}}`), 0o600); err != nil {
+		t.Fatalf("Unexpected error writing config file: %v", err)
+	}
+	noFormats := filepath.Join(t.TempDir(), "no-formats.json")
+	if err := os.WriteFile(noFormats, []byte(`{"formats": {"dateLayouts": []}}`), 0o600); err != nil {
+		t.Fatalf("Unexpected error writing config file: %v", err)
+	}
+
+	type testCase struct {
+		args        []string
+		env         env
+		want        func(c *config.Config)
+		printConfig bool
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		// defaults
+		{want: func(c *config.Config) {}},
+		// each layer overrides the last
+		{args: []string{"-config", file}, want: func(c *config.Config) {
+			c.GRPCAddr, c.HTTPAddr, c.Timeouts.Read, c.LogLevel = ":9090", "127.0.0.1:9091", config.Duration(5*time.Second), "debug"
+		}},
+		{env: env{"RECEIPT_CONFIG": file, "RECEIPT_GRPC_ADDR": ":7070", "RECEIPT_READ_TIMEOUT": "7s"}, want: func(c *config.Config) {
+			c.GRPCAddr, c.HTTPAddr, c.Timeouts.Read, c.LogLevel = ":7070", "127.0.0.1:9091", config.Duration(7*time.Second), "debug"
+		}},
+		{args: []string{"--config", file, "--grpc-addr", ":6060", "--read-timeout=1m"}, env: env{"RECEIPT_GRPC_ADDR": ":7070", "RECEIPT_LOG_LEVEL": "warn"}, want: func(c *config.Config) {
+			c.GRPCAddr, c.HTTPAddr, c.Timeouts.Read, c.LogLevel = ":6060", "127.0.0.1:9091", config.Duration(time.Minute), "warn"
+		}},
+		{args: []string{"-log-format", "json"}, env: env{"RECEIPT_LOG_FORMAT": "text"}, want: func(c *config.Config) {
+			c.LogFormat = "json"
+		}},
+		// flags set to a default value still override
+		{args: []string{"-grpc-addr", ":80"}, env: env{"RECEIPT_GRPC_ADDR": ":7070"}, want: func(c *config.Config) {}},
+		{args: []string{"--print-config"}, want: func(c *config.Config) {}, printConfig: true},
+		{args: []string{"-tls-cert", "cert.pem", "-tls-key", "key.pem", "-tls-client-ca", "cas.pem"}, env: env{"RECEIPT_TLS_REQUIRE_CLIENT_CERT": "true"}, want: func(c *config.Config) {
+			c.TLS.CertFile, c.TLS.KeyFile, c.TLS.ClientCAFile, c.TLS.RequireClientCert = "cert.pem", "key.pem", "cas.pem", true
+		}},
+		{args: []string{"-jwks", "jwks.json", "-jwt-issuer", "https://id.example.com", "-jwt-leeway", "30s"}, env: env{"RECEIPT_JWT_AUDIENCE": "receipts"}, want: func(c *config.Config) {
+			c.Auth.JWKSFile, c.Auth.Issuer, c.Auth.Audience, c.Auth.Leeway = "jwks.json", "https://id.example.com", "receipts", config.Duration(30*time.Second)
+		}},
+		{args: []string{"-trace-exporter", "file", "-trace-file", "spans.json"}, env: env{"RECEIPT_TRACE_SAMPLE_RATIO": "0.25"}, want: func(c *config.Config) {
+			c.Tracing.Exporter, c.Tracing.File, c.Tracing.SampleRatio = "file", "spans.json", 0.25
+		}},
+		{args: []string{"-limits", "receipt-processor/data/limits.json"}, want: func(c *config.Config) {
+			c.Limits.File = "receipt-processor/data/limits.json"
+		}},
+		{args: []string{"-limits", "none"}, env: env{"RECEIPT_LIMITS_FILE": "receipt-processor/data/limits.json"}, want: func(c *config.Config) {
+			c.Limits.File = "none"
+		}},
+		{args: []string{"-max-items", "50", "-max-age", "2160h", "-required-fields", "retailer, total,userId"}, env: env{"RECEIPT_MAX_TOTAL": "5000", "RECEIPT_MAX_ITEMS": "100"}, want: func(c *config.Config) {
+			c.Validation.MaxItems, c.Validation.MaxTotal, c.Validation.MaxAge = 50, 5000, config.Duration(90*24*time.Hour)
+			c.Validation.RequiredFields = []string{"retailer", "total", "userId"}
+		}},
+		{env: env{"RECEIPT_MAX_DESCRIPTION_LENGTH": "0", "RECEIPT_MAX_FUTURE": "0s", "RECEIPT_REQUIRED_FIELDS": "items"}, want: func(c *config.Config) {
+			c.Validation.MaxDescriptionLength, c.Validation.MaxFuture, c.Validation.RequiredFields = 0, 0, []string{"items"}
+		}},
+		{args: []string{"-config", formats}, want: func(c *config.Config) {
+			c.Formats.DateLayouts, c.Formats.TimeLayouts = []string{"2.1.2006", "Jan 2, 2006"}, []string{"15:04"}
+		}},
+		// invalid settings
+		{args: []string{"-grpc-addr", "80"}, errExpected: true},
+		{args: []string{"-http-addr", ":80"}, errExpected: true},
+		{args: []string{"-store", "postgres"}, errExpected: true},
+		{args: []string{"-log-level", "verbose"}, errExpected: true},
+		{env: env{"RECEIPT_LOG_FORMAT": "logfmt"}, errExpected: true},
+		{args: []string{"-idle-timeout", "-1s"}, errExpected: true},
+		{args: []string{"-idle-timeout", "soon"}, errExpected: true},
+		{env: env{"RECEIPT_WRITE_TIMEOUT": "soon"}, errExpected: true},
+		{args: []string{"-tls-cert", "cert.pem"}, errExpected: true},
+		{args: []string{"-tls-client-ca", "cas.pem"}, errExpected: true},
+		{args: []string{"-tls-cert", "cert.pem", "-tls-key", "key.pem", "-tls-require-client-cert"}, errExpected: true},
+		{env: env{"RECEIPT_TLS_REQUIRE_CLIENT_CERT": "sometimes"}, errExpected: true},
+		{args: []string{"-jwks", "jwks.json", "-jwt-issuer", "https://id.example.com"}, errExpected: true},
+		{args: []string{"-jwt-leeway", "-1s"}, errExpected: true},
+		{args: []string{"-trace-exporter", "jaeger"}, errExpected: true},
+		{args: []string{"-trace-exporter", "file"}, errExpected: true},
+		{args: []string{"-trace-file", "spans.json"}, errExpected: true},
+		{args: []string{"-trace-sample-ratio", "1.5"}, errExpected: true},
+		{env: env{"RECEIPT_TRACE_SAMPLE_RATIO": "most"}, errExpected: true},
+		{args: []string{"-max-items", "-1"}, errExpected: true},
+		{env: env{"RECEIPT_MAX_ITEMS": "many"}, errExpected: true},
+		{args: []string{"-max-future", "-1h"}, errExpected: true},
+		{args: []string{"-required-fields", "retailer,notes"}, errExpected: true},
+		{args: []string{"-config", noFormats}, errExpected: true},
+		{args: []string{"-config", filepath.Join(t.TempDir(), "missing.json")}, errExpected: true},
+		{args: []string{"-port", "80"}, errExpected: true},
+		{args: []string{"serve"}, errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		if tc.env == nil {
+			tc.env = env{}
+		}
+		c, printConfig, err := config.Load(tc.args, tc.env.Getenv)
+		if err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error loading configuration in test case %d: %v", i+1, err)
+			continue
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		want := config.Default()
+		tc.want(&want)
+		if !reflect.DeepEqual(c, want) || printConfig != tc.printConfig {
+			t.Errorf("Wrong configuration in test case %d: expected %+v, received %+v", i+1, want, c)
+		}
+	}
+}
+
+func TestLimits_Enabled(t *testing.T) {
+	type testCase struct {
+		env  env
+		want bool
+	}
+
+	var testCases = []testCase{
+		// clients aren't limited by default
+		{env: env{}, want: false},
+		{env: env{"RECEIPT_LIMITS_FILE": "receipt-processor/data/limits.json"}, want: true},
+		// an empty variable leaves the setting as it was, so limits are turned off by none
+		{env: env{"RECEIPT_LIMITS_FILE": ""}, want: false},
+		{env: env{"RECEIPT_LIMITS_FILE": "none"}, want: false},
+	}
+
+	for i, tc := range testCases {
+		c, _, err := config.Load(nil, tc.env.Getenv)
+		if err != nil {
+			t.Errorf("Unexpected error loading configuration in test case %d: %v", i+1, err)
+		} else if enabled := c.Limits.Enabled(); enabled != tc.want {
+			t.Errorf("Wrong rate limiting in test case %d: expected %t, received %t", i+1, tc.want, enabled)
+		}
+	}
+}
+
+func TestConfig_Print(t *testing.T) {
+	// the printed configuration can be read back as a config file
+	c := config.Default()
+	c.GRPCAddr, c.Timeouts.Shutdown = ":9090", config.Duration(90*time.Second)
+
+	var b bytes.Buffer
+	if err := c.Print(&b); err != nil {
+		t.Fatalf("Unexpected error printing configuration: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, b.Bytes(), 0o600); err != nil {
+		t.Fatalf("Unexpected error writing config file: %v", err)
+	}
+
+	if loaded, _, err := config.Load([]string{"-config", file}, env{}.Getenv); err != nil {
+		t.Errorf("Unexpected error loading printed configuration: %v", err)
+	} else if !reflect.DeepEqual(loaded, c) {
+		t.Errorf("Printed configuration did not round trip: expected %+v, received %+v", c, loaded)
+	}
+}
+
+func TestDialAddr(t *testing.T) {
+	type testCase struct {
+		listen string
+		dial   string
+	}
+
+	var testCases = []testCase{
+		{listen: ":80", dial: "localhost:80"},
+		{listen: "0.0.0.0:9090", dial: "localhost:9090"},
+		{listen: "[::]:9090", dial: "localhost:9090"},
+		{listen: "127.0.0.1:9090", dial: "127.0.0.1:9090"},
+		{listen: "[::1]:9090", dial: "[::1]:9090"},
+	}
+
+	for i, tc := range testCases {
+		if dial := config.DialAddr(tc.listen); dial != tc.dial {
+			t.Errorf("Wrong dial address in test case %d: expected %q, received %q", i+1, tc.dial, dial)
+		}
+	}
+}
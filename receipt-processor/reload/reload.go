@@ -0,0 +1,64 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// A Reloadable holds something read from files, which it can re-read when they change.
+type Reloadable interface {
+	// Reload re-reads the files if they've changed since they were last read, reporting whether they had.
+	// On error the previously read contents are kept.
+	Reload() (changed bool, err error)
+}
+
+// Watch reloads each file-backed value every interval until the context is done,
+// reporting each reload or failed attempt to onReload.
+func Watch(ctx context.Context, every time.Duration, onReload func(r Reloadable, err error), reloadables ...Reloadable) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, r := range reloadables {
+				if changed, err := r.Reload(); changed || err != nil {
+					onReload(r, err)
+				}
+			}
+		}
+	}
+}
+
+// A Stamp identifies a version of a file, by its size & modification time.
+type Stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// StampFiles returns the stamp of each file's current version, in order.
+func StampFiles(files ...string) (stamps []Stamp, err error) {
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, Stamp{size: info.Size(), modTime: info.ModTime()})
+	}
+	return stamps, nil
+}
+
+// Unchanged reports whether two sets of stamps are of the same versions of the same files.
+func Unchanged(a []Stamp, b []Stamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].size != b[i].size || !a[i].modTime.Equal(b[i].modTime) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package reload_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/reload"
+)
+
+// file is a Reloadable of a file's contents, reloaded when its stamp changes.
+type file struct {
+	path     string
+	contents string
+	stamps   []reload.Stamp
+	sync.Mutex
+}
+
+func (f *file) Reload() (changed bool, err error) {
+	stamps, err := reload.StampFiles(f.path)
+	if err != nil {
+		return false, err
+	}
+	f.Lock()
+	defer f.Unlock()
+	if reload.Unchanged(stamps, f.stamps) {
+		return false, nil
+	}
+	contents, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, err
+	}
+	f.contents, f.stamps = string(contents), stamps
+	return true, nil
+}
+
+func (f *file) read() string {
+	f.Lock()
+	defer f.Unlock()
+	return f.contents
+}
+
+func TestStampFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	os.WriteFile(a, []byte("a"), 0o600)
+	os.WriteFile(b, []byte("b"), 0o600)
+
+	first, err := reload.StampFiles(a, b)
+	if err != nil {
+		t.Fatalf("Unexpected error stamping files: %v", err)
+	}
+	if again, _ := reload.StampFiles(a, b); !reload.Unchanged(first, again) {
+		t.Errorf("Expected unchanged files to have the same stamps")
+	}
+	if fewer, _ := reload.StampFiles(a); reload.Unchanged(first, fewer) {
+		t.Errorf("Expected stamps of different files to differ")
+	}
+	os.WriteFile(b, []byte("bb"), 0o600)
+	if changed, _ := reload.StampFiles(a, b); reload.Unchanged(first, changed) {
+		t.Errorf("Expected a changed file to have a new stamp")
+	}
+	if _, err := reload.StampFiles(a, filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Did not receive expected error stamping a missing file")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "value")
+	os.WriteFile(path, []byte("first"), 0o600)
+	f := &file{path: path}
+	if _, err := f.Reload(); err != nil {
+		t.Fatalf("Unexpected error reading file: %v", err)
+	}
+
+	c, cancel := context.WithCancel(context.Background())
+	reloaded := make(chan error, 10)
+	done := make(chan struct{})
+	go func() {
+		reload.Watch(c, 10*time.Millisecond, func(r reload.Reloadable, err error) {
+			select {
+			case reloaded <- err:
+			default:
+			}
+		}, f)
+		close(done)
+	}()
+
+	// only changes & failures are reported
+	os.WriteFile(path, []byte("second!"), 0o600)
+	if err := <-reloaded; err != nil || f.read() != "second!" {
+		t.Errorf("Expected the changed file to be reloaded, received %q & %v", f.read(), err)
+	}
+	os.Remove(path)
+	if err := <-reloaded; err == nil || f.read() != "second!" {
+		t.Errorf("Expected a failed reload to be reported & the last contents kept, received %q & %v", f.read(), err)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Watch did not stop when its context was done")
+	}
+}
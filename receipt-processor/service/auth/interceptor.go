@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// API_KEY_HEADER is the metadata key (& HTTP header, through the gateway) API keys may be sent in,
+// as well as in the authorization header with the API_KEY_SCHEME.
+const (
+	API_KEY_HEADER = "x-api-key"
+	API_KEY_SCHEME = "ApiKey"
+)
+
+// A Principal is the authenticated caller of an RPC.
+type Principal struct {
+	ID     string // The id of the caller's key, or "user:" & the user's id.
+	Scopes []Scope
+	UserID string // The user a bearer token authenticated, who may only act as themselves; empty for API keys.
+}
+
+type principalKey struct{}
+
+// NewContext returns a context carrying the caller of an RPC.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the authenticated caller of an RPC, if there is one.
+func FromContext(ctx context.Context) (p Principal, ok bool) {
+	p, ok = ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// An Authenticator checks the API key or bearer token of each RPC's caller grants the scope the RPC needs.
+type Authenticator struct {
+	Keys    *KeyStore        // API keys accepted; none are when nil.
+	Tokens  *TokenVerifier   // Verifies bearer tokens; none are accepted when nil.
+	Methods map[string]Scope // The scope each RPC needs, by full method name; others need Admin.
+	Public  []string         // Full method names anyone may call, without credentials.
+}
+
+// authenticate returns a context carrying the caller of an RPC, once they're authorized to call it.
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	for _, public := range a.Public {
+		if method == public {
+			return ctx, nil
+		}
+	}
+
+	var p Principal
+	if token := bearerToken(ctx); token != "" && a.Tokens != nil {
+		subject, err := a.Tokens.Verify(token)
+		if err != nil {
+			return ctx, status.Errorf(codes.Unauthenticated, "Bearer token is invalid: %v", err)
+		}
+		p = Principal{ID: "user:" + subject, Scopes: TOKEN_SCOPES, UserID: subject}
+	} else if secret := apiKey(ctx); secret != "" && a.Keys != nil {
+		key, ok := a.Keys.Authenticate(secret)
+		if !ok {
+			return ctx, status.Error(codes.Unauthenticated, "API key is invalid or expired")
+		}
+		p = Principal{ID: key.ID, Scopes: key.Scopes}
+	} else {
+		return ctx, status.Error(codes.Unauthenticated, "An API key or bearer token is required")
+	}
+
+	scope, ok := a.Methods[method]
+	if !ok {
+		scope = Admin
+	}
+	if !p.Grants(scope) {
+		return ctx, status.Errorf(codes.PermissionDenied, "%s is not granted the %s scope", p.ID, scope)
+	}
+	return NewContext(ctx, p), nil
+}
+
+// Grants reports whether the caller was granted a scope.
+func (p *Principal) Grants(scope Scope) bool {
+	return slices.Contains(p.Scopes, Admin) || slices.Contains(p.Scopes, scope)
+}
+
+// UnaryInterceptor authenticates unary RPCs.
+func (a *Authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor authenticates streaming RPCs, such as server reflection.
+func (a *Authenticator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream is a ServerStream carrying its authenticated caller.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context { return s.ctx }
+
+// apiKey returns the API key sent with an RPC, from the API_KEY_HEADER or an authorization header.
+func apiKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(API_KEY_HEADER); len(keys) > 0 {
+		return keys[0]
+	}
+	return authorization(md, API_KEY_SCHEME)
+}
+
+// bearerToken returns the bearer token sent with an RPC, from its authorization header.
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return authorization(md, BEARER_SCHEME)
+}
+
+// authorization returns the credentials of the authorization header with a scheme.
+func authorization(md metadata.MD, scheme string) string {
+	for _, header := range md.Get("authorization") {
+		if s, credentials, ok := strings.Cut(header, " "); ok && strings.EqualFold(s, scheme) {
+			return strings.TrimSpace(credentials)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/auth"
+)
+
+func TestAuthenticator_UnaryInterceptor(t *testing.T) {
+	keys, err := auth.NewKeyStore(
+		auth.Key{ID: "mobile", Hash: auth.HashKey("mobile-secret"), Scopes: []auth.Scope{auth.Process, auth.Award}},
+		auth.Key{ID: "partner", Hash: auth.HashKey("partner-secret"), Scopes: []auth.Scope{auth.Process}},
+		auth.Key{ID: "ops", Hash: auth.HashKey("ops-secret"), Scopes: []auth.Scope{auth.Admin}},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error creating key store: %v", err)
+	}
+	a := &auth.Authenticator{
+		Keys:    keys,
+		Methods: map[string]auth.Scope{"/test/Process": auth.Process, "/test/Award": auth.Award},
+		Public:  []string{"/test/Health"},
+	}
+
+	type testCase struct {
+		method string
+		md     metadata.MD
+		wantID string
+		code   codes.Code
+	}
+
+	var testCases = []testCase{
+		{method: "/test/Process", md: metadata.Pairs("x-api-key", "partner-secret"), wantID: "partner", code: codes.OK},
+		{method: "/test/Process", md: metadata.Pairs("authorization", "ApiKey mobile-secret"), wantID: "mobile", code: codes.OK},
+		{method: "/test/Award", md: metadata.Pairs("authorization", "apikey mobile-secret"), wantID: "mobile", code: codes.OK},
+		// admin keys may call anything, including RPCs without a scope of their own
+		{method: "/test/Award", md: metadata.Pairs("x-api-key", "ops-secret"), wantID: "ops", code: codes.OK},
+		{method: "/test/Reverse", md: metadata.Pairs("x-api-key", "ops-secret"), wantID: "ops", code: codes.OK},
+		{method: "/test/Reverse", md: metadata.Pairs("x-api-key", "mobile-secret"), code: codes.PermissionDenied},
+		{method: "/test/Award", md: metadata.Pairs("x-api-key", "partner-secret"), code: codes.PermissionDenied},
+		{method: "/test/Process", md: metadata.Pairs("x-api-key", "wrong-secret"), code: codes.Unauthenticated},
+		{method: "/test/Process", md: metadata.Pairs("authorization", "Bearer mobile-secret"), code: codes.Unauthenticated},
+		{method: "/test/Process", md: metadata.MD{}, code: codes.Unauthenticated},
+		{method: "/test/Health", md: metadata.MD{}, code: codes.OK},
+	}
+
+	interceptor := a.UnaryInterceptor()
+	for i, tc := range testCases {
+		var caller auth.Principal
+		handler := func(ctx context.Context, req any) (any, error) {
+			caller, _ = auth.FromContext(ctx)
+			return nil, nil
+		}
+		ctx := metadata.NewIncomingContext(context.Background(), tc.md)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+		if code := status.Code(err); code != tc.code {
+			t.Errorf("Wrong status in test case %d: expected %s, received %s (%v)", i+1, tc.code, code, err)
+		} else if caller.ID != tc.wantID {
+			t.Errorf("Wrong caller in test case %d: expected %q, received %q", i+1, tc.wantID, caller.ID)
+		}
+	}
+}
+
+func TestAuthenticator_BearerTokens(t *testing.T) {
+	signers, jwks := newSigningKeys(t)
+	keys, err := auth.NewKeyStore(auth.Key{ID: "ops", Hash: auth.HashKey("ops-secret"), Scopes: []auth.Scope{auth.Admin}})
+	if err != nil {
+		t.Fatalf("Unexpected error creating key store: %v", err)
+	}
+	a := &auth.Authenticator{
+		Keys:    keys,
+		Tokens:  &auth.TokenVerifier{Keys: jwks, Issuer: "https://id.example.com", Audience: "receipts"},
+		Methods: map[string]auth.Scope{"/test/Process": auth.Process, "/test/Award": auth.Award},
+	}
+	token := signers.sign(t, jwt.SigningMethodES256, "ec-1", &jwt.RegisteredClaims{
+		Subject:   "user-1",
+		Issuer:    "https://id.example.com",
+		Audience:  jwt.ClaimStrings{"receipts"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+
+	type testCase struct {
+		method     string
+		md         metadata.MD
+		wantID     string
+		wantUserID string
+		code       codes.Code
+	}
+
+	var testCases = []testCase{
+		{method: "/test/Process", md: metadata.Pairs("authorization", "Bearer "+token), wantID: "user:user-1", wantUserID: "user-1", code: codes.OK},
+		{method: "/test/Award", md: metadata.Pairs("authorization", "bearer "+token), wantID: "user:user-1", wantUserID: "user-1", code: codes.OK},
+		// users may not call admin RPCs
+		{method: "/test/Reverse", md: metadata.Pairs("authorization", "Bearer "+token), code: codes.PermissionDenied},
+		{method: "/test/Process", md: metadata.Pairs("authorization", "Bearer "+token[:len(token)-2]), code: codes.Unauthenticated},
+		// API keys are still accepted alongside tokens
+		{method: "/test/Reverse", md: metadata.Pairs("x-api-key", "ops-secret"), wantID: "ops", code: codes.OK},
+	}
+
+	interceptor := a.UnaryInterceptor()
+	for i, tc := range testCases {
+		var caller auth.Principal
+		handler := func(ctx context.Context, req any) (any, error) {
+			caller, _ = auth.FromContext(ctx)
+			return nil, nil
+		}
+		ctx := metadata.NewIncomingContext(context.Background(), tc.md)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+		if code := status.Code(err); code != tc.code {
+			t.Errorf("Wrong status in test case %d: expected %s, received %s (%v)", i+1, tc.code, code, err)
+		} else if caller.ID != tc.wantID || caller.UserID != tc.wantUserID {
+			t.Errorf("Wrong caller in test case %d: expected %q (%q), received %q (%q)", i+1, tc.wantID, tc.wantUserID, caller.ID, caller.UserID)
+		}
+	}
+}
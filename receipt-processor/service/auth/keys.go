@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	reload "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/reload"
+)
+
+// A Scope grants access to a group of RPCs.
+type Scope string
+
+const (
+	Process Scope = "process" // Submitting receipts.
+	Award   Scope = "award"   // Awarding points for receipts, & spending & viewing users' points.
+	Admin   Scope = "admin"   // Every RPC, including reversals & fraud review.
+)
+
+// Scopes are the scopes keys may be granted.
+var Scopes = []Scope{Process, Award, Admin}
+
+// HASH_PREFIX marks the algorithm key hashes are written with.
+const HASH_PREFIX = "sha256:"
+
+// A Key is an API key, known only by the hash of its secret.
+// Keys are rotated by adding the new key, then retiring the old one once callers have switched, by setting its expiry.
+type Key struct {
+	ID      string    `json:"id"`      // Identifies the key's holder in logs & the ledger, without revealing the secret.
+	Hash    string    `json:"hash"`    // HASH_PREFIX followed by the hex SHA-256 hash of the secret.
+	Scopes  []Scope   `json:"scopes"`  // What the key grants access to.
+	Expires time.Time `json:"expires"` // Optional. When the key stops being accepted, in RFC 3339 format.
+}
+
+// HashKey returns the hash a key's secret is stored as.
+func HashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return HASH_PREFIX + hex.EncodeToString(sum[:])
+}
+
+// A KeyStore holds the API keys read from a JSON file, reloaded when the file changes.
+type KeyStore struct {
+	Now    func() time.Time // Clock key expiry is checked against; defaults to time.Now.
+	file   string
+	keys   map[string]Key // by hash
+	stamps []reload.Stamp
+	sync.RWMutex
+}
+
+// NewKeyStore returns a KeyStore of the given keys, which must be valid.
+func NewKeyStore(keys ...Key) (s *KeyStore, err error) {
+	s = &KeyStore{}
+	if s.keys, err = index(keys); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadKeys reads a JSON array of keys.
+func LoadKeys(path string) (s *KeyStore, err error) {
+	s = &KeyStore{file: path}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadKeysJSON reads a JSON array of keys.
+func LoadKeysJSON(r io.Reader) (s *KeyStore, err error) {
+	keys, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyStore(keys...)
+}
+
+// Reload re-reads the keys file if it's changed since it was last read, reporting whether it had.
+// On error the previously read keys are kept.
+func (s *KeyStore) Reload() (changed bool, err error) {
+	if s.file == "" {
+		return false, nil
+	}
+	stamps, err := reload.StampFiles(s.file)
+	if err != nil {
+		return false, fmt.Errorf("error reading API keys: %w", err)
+	}
+	s.RLock()
+	unchanged := reload.Unchanged(stamps, s.stamps)
+	s.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	f, err := os.Open(s.file)
+	if err != nil {
+		return false, fmt.Errorf("error reading API keys: %w", err)
+	}
+	defer f.Close()
+	keys, err := decode(f)
+	if err != nil {
+		return false, err
+	}
+	indexed, err := index(keys)
+	if err != nil {
+		return false, err
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.keys, s.stamps = indexed, stamps
+	return true, nil
+}
+
+func (s *KeyStore) String() string { return s.file }
+
+// Authenticate returns the key a secret belongs to, if it's known & hasn't expired.
+func (s *KeyStore) Authenticate(secret string) (key Key, ok bool) {
+	s.RLock()
+	defer s.RUnlock()
+	// secrets are looked up by their hash, so lookups take no longer for nearly-right secrets
+	key, ok = s.keys[HashKey(secret)]
+	if !ok || (!key.Expires.IsZero() && !s.now().Before(key.Expires)) {
+		return Key{}, false
+	}
+	return key, true
+}
+
+// now returns the current time, as seen by the store's clock.
+func (s *KeyStore) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+// Validate reports configuration errors in the key.
+func (k *Key) Validate() (err error) {
+	if k.ID == "" {
+		return fmt.Errorf("API key has no id")
+	} else if digest, ok := strings.CutPrefix(k.Hash, HASH_PREFIX); !ok {
+		return fmt.Errorf("API key %s hash must begin with %s", k.ID, HASH_PREFIX)
+	} else if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("API key %s hash is not a hex SHA-256 hash", k.ID)
+	} else if len(k.Scopes) == 0 {
+		return fmt.Errorf("API key %s has no scopes", k.ID)
+	}
+	for _, scope := range k.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("API key %s has unknown scope %q", k.ID, scope)
+		}
+	}
+	return nil
+}
+
+func decode(r io.Reader) (keys []Key, err error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&keys); err != nil {
+		return nil, fmt.Errorf("error decoding API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// index validates keys, returning them by hash.
+func index(keys []Key) (indexed map[string]Key, err error) {
+	indexed = make(map[string]Key)
+	ids := make(map[string]bool)
+	for _, k := range keys {
+		if err := k.Validate(); err != nil {
+			return nil, err
+		} else if ids[k.ID] {
+			return nil, fmt.Errorf("API key %s is defined more than once", k.ID)
+		} else if _, exists := indexed[strings.ToLower(k.Hash)]; exists {
+			return nil, fmt.Errorf("API key %s has the same secret as another key", k.ID)
+		}
+		ids[k.ID] = true
+		indexed[strings.ToLower(k.Hash)] = k
+	}
+	return indexed, nil
+}
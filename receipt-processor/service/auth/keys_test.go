@@ -0,0 +1,116 @@
+package auth_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/auth"
+)
+
+func TestLoadKeysJSON(t *testing.T) {
+	hash := auth.HashKey("secret")
+
+	type testCase struct {
+		json        string
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{json: fmt.Sprintf(`[{"id": "mobile", "hash": %q, "scopes": ["process", "award"]}]`, hash)},
+		{json: fmt.Sprintf(`[{"id": "mobile", "hash": %q, "scopes": ["admin"], "expires": "2026-01-01T00:00:00Z"}]`, hash)},
+		{json: `[]`},
+		{json: fmt.Sprintf(`[{"id": "", "hash": %q, "scopes": ["process"]}]`, hash), errExpected: true},
+		{json: `[{"id": "mobile", "hash": "secret", "scopes": ["process"]}]`, errExpected: true},
+		{json: `[{"id": "mobile", "hash": "sha256:abc", "scopes": ["process"]}]`, errExpected: true},
+		{json: fmt.Sprintf(`[{"id": "mobile", "hash": %q, "scopes": []}]`, hash), errExpected: true},
+		{json: fmt.Sprintf(`[{"id": "mobile", "hash": %q, "scopes": ["everything"]}]`, hash), errExpected: true},
+		{json: fmt.Sprintf(`[{"id": "mobile", "hash": %q, "scopes": ["process"]}, {"id": "mobile", "hash": %q, "scopes": ["process"]}]`, hash, auth.HashKey("other")), errExpected: true},
+		{json: fmt.Sprintf(`[{"id": "mobile", "hash": %q, "scopes": ["process"]}, {"id": "web", "hash": %q, "scopes": ["process"]}]`, hash, strings.ToUpper(hash[7:])), errExpected: true},
+		{json: fmt.Sprintf(`[{"id": "mobile", "hash": %q, "scopes": ["process"], "secret": "secret"}]`, hash), errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		if _, err := auth.LoadKeysJSON(strings.NewReader(tc.json)); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error loading keys in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+}
+
+func TestKeyStore_Authenticate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	s, err := auth.NewKeyStore(
+		// the old key is being rotated out, & stops working at the end of June
+		auth.Key{ID: "mobile-2024", Hash: auth.HashKey("old-secret"), Scopes: []auth.Scope{auth.Process}, Expires: now.AddDate(0, 1, 0)},
+		auth.Key{ID: "mobile-2025", Hash: auth.HashKey("new-secret"), Scopes: []auth.Scope{auth.Process}},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error creating key store: %v", err)
+	}
+	s.Now = func() time.Time { return now }
+
+	type testCase struct {
+		secret string
+		at     time.Time
+		wantID string
+	}
+
+	var testCases = []testCase{
+		{secret: "old-secret", at: now, wantID: "mobile-2024"},
+		{secret: "new-secret", at: now, wantID: "mobile-2025"},
+		{secret: "old-secret", at: now.AddDate(0, 1, 0), wantID: ""},
+		{secret: "new-secret", at: now.AddDate(1, 0, 0), wantID: "mobile-2025"},
+		{secret: "new-secret ", at: now, wantID: ""},
+		{secret: "", at: now, wantID: ""},
+	}
+
+	for i, tc := range testCases {
+		now = tc.at
+		if key, ok := s.Authenticate(tc.secret); key.ID != tc.wantID || ok != (tc.wantID != "") {
+			t.Errorf("Wrong key authenticated in test case %d: expected %q, received %q", i+1, tc.wantID, key.ID)
+		}
+	}
+}
+
+func TestKeyStore_Reload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys.json")
+	write := func(json string, modified time.Time) {
+		if err := os.WriteFile(file, []byte(json), 0o600); err != nil {
+			t.Fatalf("Unexpected error writing keys: %v", err)
+		}
+		os.Chtimes(file, modified, modified)
+	}
+	write(fmt.Sprintf(`[{"id": "old", "hash": %q, "scopes": ["process"]}]`, auth.HashKey("old-secret")), time.Now())
+
+	s, err := auth.LoadKeys(file)
+	if err != nil {
+		t.Fatalf("Unexpected error loading keys: %v", err)
+	}
+	if changed, err := s.Reload(); changed || err != nil {
+		t.Errorf("Expected no reload of an unchanged file, received %t & %v", changed, err)
+	}
+
+	// rotating in a new key
+	write(fmt.Sprintf(`[{"id": "old", "hash": %q, "scopes": ["process"]}, {"id": "new", "hash": %q, "scopes": ["process"]}]`,
+		auth.HashKey("old-secret"), auth.HashKey("new-secret")), time.Now().Add(time.Minute))
+	if changed, err := s.Reload(); !changed || err != nil {
+		t.Errorf("Expected the keys to be reloaded, received %t & %v", changed, err)
+	}
+	if _, ok := s.Authenticate("new-secret"); !ok {
+		t.Errorf("Expected the new key to be accepted once reloaded")
+	}
+
+	// a broken file keeps the last good keys
+	write(`[{"id": "new"`, time.Now().Add(2*time.Minute))
+	if _, err := s.Reload(); err == nil {
+		t.Errorf("Expected an error reloading invalid keys")
+	}
+	if _, ok := s.Authenticate("old-secret"); !ok {
+		t.Errorf("Expected the previous keys to still be accepted")
+	}
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	reload "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/reload"
+)
+
+// BEARER_SCHEME is the authorization scheme JWTs are sent with.
+const BEARER_SCHEME = "Bearer"
+
+// TOKEN_SCOPES are granted to users authenticated by a bearer token, who may only act as themselves.
+var TOKEN_SCOPES = []Scope{Process, Award}
+
+// A JWK is a public key in a JSON Web Key Set; only RSA & P-256 EC signing keys are read.
+type JWK struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// A JWKS holds the public keys tokens are signed with, read from a JSON Web Key Set file, reloaded when it changes.
+type JWKS struct {
+	file   string
+	keys   map[string]crypto.PublicKey // by key id
+	stamps []reload.Stamp
+	sync.RWMutex
+}
+
+// LoadJWKS reads a JSON Web Key Set file.
+func LoadJWKS(path string) (s *JWKS, err error) {
+	s = &JWKS{file: path}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadJWKSJSON reads a JSON Web Key Set.
+func LoadJWKSJSON(r io.Reader) (s *JWKS, err error) {
+	s = &JWKS{}
+	if s.keys, err = decodeJWKS(r); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the key set file if it's changed since it was last read, reporting whether it had.
+// On error the previously read keys are kept.
+func (s *JWKS) Reload() (changed bool, err error) {
+	if s.file == "" {
+		return false, nil
+	}
+	stamps, err := reload.StampFiles(s.file)
+	if err != nil {
+		return false, fmt.Errorf("error reading JWKS: %w", err)
+	}
+	s.RLock()
+	unchanged := reload.Unchanged(stamps, s.stamps)
+	s.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	f, err := os.Open(s.file)
+	if err != nil {
+		return false, fmt.Errorf("error reading JWKS: %w", err)
+	}
+	defer f.Close()
+	keys, err := decodeJWKS(f)
+	if err != nil {
+		return false, err
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.keys, s.stamps = keys, stamps
+	return true, nil
+}
+
+func (s *JWKS) String() string { return s.file }
+
+// Key returns the public key with an id.
+func (s *JWKS) Key(id string) (key crypto.PublicKey, ok bool) {
+	s.RLock()
+	defer s.RUnlock()
+	key, ok = s.keys[id]
+	return key, ok
+}
+
+// A TokenVerifier validates RS256 & ES256 bearer tokens against a JWKS, and the issuer, audience & expiry they claim.
+type TokenVerifier struct {
+	Keys     *JWKS
+	Issuer   string
+	Audience string
+	Leeway   time.Duration    // Clock skew tolerated when checking expiry & not-before times.
+	Now      func() time.Time // Clock tokens are checked against; defaults to time.Now.
+}
+
+// Verify returns the subject of a valid token: the user it authenticates.
+func (v *TokenVerifier) Verify(token string) (subject string, err error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuer(v.Issuer),
+		jwt.WithAudience(v.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.Leeway),
+	}
+	if v.Now != nil {
+		opts = append(opts, jwt.WithTimeFunc(v.Now))
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, v.key, opts...); err != nil {
+		return "", err
+	} else if claims.Subject == "" {
+		return "", errors.New("token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// key returns the public key a token names as its signer, as long as it's of the type its algorithm needs.
+func (v *TokenVerifier) key(token *jwt.Token) (any, error) {
+	id, _ := token.Header["kid"].(string)
+	key, ok := v.Keys.Key(id)
+	if !ok {
+		return nil, fmt.Errorf("token is signed by unknown key %q", id)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("key %q is an RSA key, but the token is signed with %s", id, token.Method.Alg())
+		}
+	case *ecdsa.PublicKey:
+		if token.Method != jwt.SigningMethodES256 {
+			return nil, fmt.Errorf("key %q is an EC key, but the token is signed with %s", id, token.Method.Alg())
+		}
+	}
+	return key, nil
+}
+
+func decodeJWKS(r io.Reader) (keys map[string]crypto.PublicKey, err error) {
+	var set struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&set); err != nil {
+		return nil, fmt.Errorf("error decoding JWKS: %w", err)
+	}
+
+	keys = make(map[string]crypto.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		} else if jwk.KeyID == "" {
+			return nil, errors.New("JWKS has a key without a kid")
+		} else if _, exists := keys[jwk.KeyID]; exists {
+			return nil, fmt.Errorf("JWKS has more than one key %q", jwk.KeyID)
+		}
+		if keys[jwk.KeyID], err = jwk.PublicKey(); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// PublicKey returns the RSA or EC public key a JWK describes.
+func (jwk *JWK) PublicKey() (key crypto.PublicKey, err error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, errN := decodeInt(jwk.N)
+		e, errE := decodeInt(jwk.E)
+		if errN != nil || errE != nil || !e.IsInt64() || n.BitLen() < 2048 {
+			return nil, fmt.Errorf("JWK %q is not a valid RSA key of at least 2048 bits", jwk.KeyID)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if jwk.Curve != "P-256" {
+			return nil, fmt.Errorf("JWK %q uses unsupported curve %q", jwk.KeyID, jwk.Curve)
+		}
+		x, errX := decodeInt(jwk.X)
+		y, errY := decodeInt(jwk.Y)
+		if errX != nil || errY != nil || x.BitLen() > 256 || y.BitLen() > 256 {
+			return nil, fmt.Errorf("JWK %q is not a valid P-256 key", jwk.KeyID)
+		}
+		// the point is checked to be on the curve by parsing it in uncompressed form
+		point := append([]byte{4}, append(x.FillBytes(make([]byte, 32)), y.FillBytes(make([]byte, 32))...)...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("JWK %q is not a valid P-256 key", jwk.KeyID)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("JWK %q has unsupported key type %q", jwk.KeyID, jwk.KeyType)
+	}
+}
+
+// decodeInt decodes a base64url-encoded big-endian integer.
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
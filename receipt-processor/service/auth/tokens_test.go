@@ -0,0 +1,151 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/auth"
+)
+
+// signingKeys are the private keys the test JWKS publishes the public halves of.
+type signingKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newSigningKeys(t *testing.T) (keys signingKeys, jwks *auth.JWKS) {
+	t.Helper()
+	var err error
+	if keys.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatalf("Unexpected error generating RSA key: %v", err)
+	} else if keys.ec, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatalf("Unexpected error generating EC key: %v", err)
+	}
+	set, _ := json.Marshal(map[string][]auth.JWK{"keys": {
+		{KeyID: "rsa-1", KeyType: "RSA", Use: "sig", N: encodeInt(keys.rsa.N), E: encodeInt(big.NewInt(int64(keys.rsa.E)))},
+		{KeyID: "ec-1", KeyType: "EC", Curve: "P-256", X: encodeInt(keys.ec.X), Y: encodeInt(keys.ec.Y)},
+		// keys for other uses are skipped
+		{KeyID: "enc-1", KeyType: "RSA", Use: "enc"},
+	}})
+	if jwks, err = auth.LoadJWKSJSON(strings.NewReader(string(set))); err != nil {
+		t.Fatalf("Unexpected error loading JWKS: %v", err)
+	}
+	return keys, jwks
+}
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// sign returns a token of the claims, signed with the method & the test key named kid.
+func (keys signingKeys) sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	var key any
+	switch method {
+	case jwt.SigningMethodRS256:
+		key = keys.rsa
+	case jwt.SigningMethodES256:
+		key = keys.ec
+	case jwt.SigningMethodHS256:
+		key = []byte("shared-secret")
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Unexpected error signing token: %v", err)
+	}
+	return signed
+}
+
+func TestTokenVerifier_Verify(t *testing.T) {
+	keys, jwks := newSigningKeys(t)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	v := &auth.TokenVerifier{Keys: jwks, Issuer: "https://id.example.com", Audience: "receipts", Leeway: time.Minute, Now: func() time.Time { return now }}
+
+	claims := func(modify func(c *jwt.RegisteredClaims)) jwt.Claims {
+		c := &jwt.RegisteredClaims{
+			Subject:   "user-1",
+			Issuer:    "https://id.example.com",
+			Audience:  jwt.ClaimStrings{"receipts"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+
+	type testCase struct {
+		token       string
+		subject     string
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{token: keys.sign(t, jwt.SigningMethodRS256, "rsa-1", claims(nil)), subject: "user-1"},
+		{token: keys.sign(t, jwt.SigningMethodES256, "ec-1", claims(nil)), subject: "user-1"},
+		// expiry is checked with leeway for clock skew
+		{token: keys.sign(t, jwt.SigningMethodES256, "ec-1", claims(func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-30 * time.Second)) })), subject: "user-1"},
+		{token: keys.sign(t, jwt.SigningMethodES256, "ec-1", claims(func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-2 * time.Minute)) })), errExpected: true},
+		{token: keys.sign(t, jwt.SigningMethodRS256, "rsa-1", claims(func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil })), errExpected: true},
+		{token: keys.sign(t, jwt.SigningMethodRS256, "rsa-1", claims(func(c *jwt.RegisteredClaims) { c.NotBefore = jwt.NewNumericDate(now.Add(time.Hour)) })), errExpected: true},
+		{token: keys.sign(t, jwt.SigningMethodRS256, "rsa-1", claims(func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"other"} })), errExpected: true},
+		{token: keys.sign(t, jwt.SigningMethodRS256, "rsa-1", claims(func(c *jwt.RegisteredClaims) { c.Issuer = "https://evil.example.com" })), errExpected: true},
+		{token: keys.sign(t, jwt.SigningMethodRS256, "rsa-1", claims(func(c *jwt.RegisteredClaims) { c.Subject = "" })), errExpected: true},
+		// the key must be known, & of the type the algorithm needs
+		{token: keys.sign(t, jwt.SigningMethodRS256, "rsa-2", claims(nil)), errExpected: true},
+		{token: keys.sign(t, jwt.SigningMethodRS256, "enc-1", claims(nil)), errExpected: true},
+		{token: keys.sign(t, jwt.SigningMethodES256, "rsa-1", claims(nil)), errExpected: true},
+		{token: keys.sign(t, jwt.SigningMethodHS256, "rsa-1", claims(nil)), errExpected: true},
+		{token: keys.sign(t, jwt.SigningMethodRS256, "rsa-1", claims(nil)) + "x", errExpected: true},
+		{token: "not-a-token", errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		if subject, err := v.Verify(tc.token); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error verifying token in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		} else if subject != tc.subject {
+			t.Errorf("Wrong subject in test case %d: expected %q, received %q", i+1, tc.subject, subject)
+		}
+	}
+}
+
+func TestLoadJWKSJSON(t *testing.T) {
+	type testCase struct {
+		json        string
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{json: `{"keys": []}`},
+		{json: `{"keys": [{"kid": "enc", "kty": "oct", "use": "enc"}]}`},
+		{json: `{"keys": [{"kid": "hmac", "kty": "oct", "k": "c2VjcmV0"}]}`, errExpected: true},
+		{json: `{"keys": [{"kty": "RSA", "n": "AQAB", "e": "AQAB"}]}`, errExpected: true},
+		// RSA keys must be at least 2048 bits
+		{json: `{"keys": [{"kid": "short", "kty": "RSA", "n": "` + strings.Repeat("A", 170) + `", "e": "AQAB"}]}`, errExpected: true},
+		{json: `{"keys": [{"kid": "p384", "kty": "EC", "crv": "P-384", "x": "AQAB", "y": "AQAB"}]}`, errExpected: true},
+		{json: `{"keys": [{"kid": "off-curve", "kty": "EC", "crv": "P-256", "x": "AQAB", "y": "AQAB"}]}`, errExpected: true},
+		{json: `[]`, errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		if _, err := auth.LoadJWKSJSON(strings.NewReader(tc.json)); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error loading JWKS in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package fraud
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// An Outcome is what's done with a receipt, given its fraud score.
+type Outcome string
+
+const (
+	Allow  Outcome = "ALLOW"  // The receipt is stored & awarded as usual.
+	Flag   Outcome = "FLAG"   // The receipt is stored & awarded, but listed for review.
+	Hold   Outcome = "HOLD"   // The receipt is stored, but no points are awarded until it's reviewed.
+	Reject Outcome = "REJECT" // The receipt is not stored.
+)
+
+// A Signal scores one indication that a receipt is fraudulent.
+type Signal interface {
+	// Name identifies the signal in assessments.
+	Name() string
+	// Score returns how strongly the receipt shows the signal, from 0 (not at all) upward, & why.
+	// A score of 1 is, on its own, enough to hold a receipt under the default thresholds.
+	Score(r *model.Receipt) (score float64, reason string)
+}
+
+// A Recorder is a Signal which learns from the receipts screened, such as to track each user's submissions.
+type Recorder interface {
+	Signal
+	// Record notes a receipt which wasn't rejected.
+	Record(r *model.Receipt)
+}
+
+// A Finding is a signal's contribution to a receipt's score.
+type Finding struct {
+	Signal string
+	Score  float64
+	Reason string
+}
+
+// An Assessment is the outcome of scoring a receipt.
+type Assessment struct {
+	ReceiptID  string // Empty for rejected receipts, which aren't stored.
+	UserID     string
+	Retailer   string
+	Outcome    Outcome
+	Score      float64
+	Findings   []Finding
+	AssessedAt time.Time
+}
+
+// Default thresholds a receipt's total score is compared against.
+const (
+	DefaultFlagAt   = 0.5
+	DefaultHoldAt   = 1.0
+	DefaultRejectAt = 2.0
+)
+
+// DefaultMaxFlagged is how many assessments are kept for review, by default.
+const DefaultMaxFlagged = 10000
+
+// A Scorer sums the scores of its signals for each receipt, & keeps the assessments of those it didn't allow until they're reviewed.
+type Scorer struct {
+	Signals    []Signal
+	FlagAt     float64          // Scores at or above this are flagged.
+	HoldAt     float64          // Scores at or above this are held.
+	RejectAt   float64          // Scores at or above this are rejected.
+	MaxFlagged int              // Most assessments kept for review, the oldest dropped first; unbounded when zero.
+	Now        func() time.Time // Clock assessments are timestamped with; defaults to time.Now.
+	flagged    []Assessment
+	screening  sync.Mutex // Held while a receipt is screened, so each is scored after the last was learned from.
+	sync.Mutex
+}
+
+// NewScorer returns a Scorer of the given signals, with the default thresholds & review limit.
+func NewScorer(signals ...Signal) *Scorer {
+	return &Scorer{Signals: signals, FlagAt: DefaultFlagAt, HoldAt: DefaultHoldAt, RejectAt: DefaultRejectAt, MaxFlagged: DefaultMaxFlagged}
+}
+
+// DefaultSignals returns a fresh set of the built-in signals, with their default settings.
+func DefaultSignals() []Signal {
+	return []Signal{
+		&RuleMaximizing{},
+		&DuplicateItems{MinItems: DefaultMinDuplicateItems},
+		&ImprobableTotal{Max: DefaultImprobableTotal},
+		NewVelocity(DefaultVelocityMax, DefaultVelocityWindow),
+	}
+}
+
+// Validate reports configuration errors in the scorer's thresholds.
+func (s *Scorer) Validate() (err error) {
+	if s.FlagAt <= 0 || s.HoldAt <= 0 || s.RejectAt <= 0 {
+		return fmt.Errorf("fraud thresholds must be positive")
+	} else if s.FlagAt > s.HoldAt || s.HoldAt > s.RejectAt {
+		return fmt.Errorf("fraud thresholds must increase from flag, to hold, to reject")
+	} else if s.MaxFlagged < 0 {
+		return fmt.Errorf("the most assessments kept for review must not be negative")
+	}
+	return nil
+}
+
+// Assess scores a receipt, without its signals learning from it.
+func (s *Scorer) Assess(r *model.Receipt) (a Assessment) {
+	a = Assessment{UserID: r.UserID, Retailer: r.Retailer, Outcome: Allow, Findings: make([]Finding, 0), AssessedAt: s.now()}
+	for _, signal := range s.Signals {
+		if score, reason := signal.Score(r); score > 0 {
+			a.Findings = append(a.Findings, Finding{Signal: signal.Name(), Score: score, Reason: reason})
+			a.Score += score
+		}
+	}
+	if a.Score >= s.RejectAt {
+		a.Outcome = Reject
+	} else if a.Score >= s.HoldAt {
+		a.Outcome = Hold
+	} else if a.Score >= s.FlagAt {
+		a.Outcome = Flag
+	}
+	return a
+}
+
+// Screen scores a receipt & has its signals learn from it, unless it's rejected, before the next receipt is screened,
+// so receipts submitted at once can't each slip under a limit like Velocity's. A receipt which then fails to be stored
+// is still learned from, erring on the side of caution.
+func (s *Scorer) Screen(r *model.Receipt) (a Assessment) {
+	s.screening.Lock()
+	defer s.screening.Unlock()
+	a = s.Assess(r)
+	if a.Outcome != Reject {
+		for _, signal := range s.Signals {
+			if recorder, ok := signal.(Recorder); ok {
+				recorder.Record(r)
+			}
+		}
+	}
+	return a
+}
+
+// Record keeps the assessment of a screened receipt for review, with the id it was stored under, if it was,
+// unless it was allowed.
+func (s *Scorer) Record(id string, a Assessment) {
+	if a.Outcome == Allow {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	a.ReceiptID = id
+	s.flagged = append(s.flagged, a)
+	// the oldest are dropped past the limit; a held receipt dropped can still be reviewed by its id
+	if s.MaxFlagged > 0 && len(s.flagged) > s.MaxFlagged {
+		s.flagged = slices.Delete(s.flagged, 0, len(s.flagged)-s.MaxFlagged)
+	}
+}
+
+// Reviewed stops listing the assessment of a stored receipt for review, reporting whether it was listed.
+func (s *Scorer) Reviewed(id string) (listed bool) {
+	s.Lock()
+	defer s.Unlock()
+	for i, a := range s.flagged {
+		if a.ReceiptID == id && id != "" {
+			s.flagged = slices.Delete(s.flagged, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// Flagged returns the assessments kept for review, most recent first, optionally only those with an outcome.
+func (s *Scorer) Flagged(outcome Outcome) (flagged []Assessment) {
+	s.Lock()
+	defer s.Unlock()
+	flagged = make([]Assessment, 0)
+	for i := len(s.flagged) - 1; i >= 0; i-- {
+		if outcome == "" || s.flagged[i].Outcome == outcome {
+			flagged = append(flagged, s.flagged[i])
+		}
+	}
+	return flagged
+}
+
+// ParseOutcome returns the Outcome named, ignoring case.
+func ParseOutcome(name string) (outcome Outcome, err error) {
+	switch outcome = Outcome(strings.ToUpper(strings.TrimSpace(name))); outcome {
+	case Allow, Flag, Hold, Reject:
+		return outcome, nil
+	default:
+		return "", fmt.Errorf("unknown fraud outcome %q", name)
+	}
+}
+
+// Reasons returns the reasons of an assessment's findings, strongest first.
+func (a *Assessment) Reasons() (reasons []string) {
+	findings := append([]Finding(nil), a.Findings...)
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Score > findings[j].Score })
+	reasons = make([]string, 0, len(findings))
+	for _, f := range findings {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", f.Signal, f.Reason))
+	}
+	return reasons
+}
+
+// now returns the current time, as seen by the scorer's clock.
+func (s *Scorer) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
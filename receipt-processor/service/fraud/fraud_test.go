@@ -0,0 +1,197 @@
+package fraud_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/fraud"
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// clock is a settable clock.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+// fixed is a Signal scoring every receipt the same.
+type fixed float64
+
+func (f fixed) Name() string { return "fixed" }
+
+func (f fixed) Score(r *model.Receipt) (score float64, reason string) { return float64(f), "always" }
+
+func TestScorer_Assess(t *testing.T) {
+	type testCase struct {
+		signals []fraud.Signal
+		outcome fraud.Outcome
+	}
+
+	var testCases = []testCase{
+		{signals: nil, outcome: fraud.Allow},
+		{signals: []fraud.Signal{fixed(0.4)}, outcome: fraud.Allow},
+		{signals: []fraud.Signal{fixed(0.5)}, outcome: fraud.Flag},
+		{signals: []fraud.Signal{fixed(0.5), fixed(0.5)}, outcome: fraud.Hold},
+		{signals: []fraud.Signal{fixed(1), fixed(0.5), fixed(0.5)}, outcome: fraud.Reject},
+	}
+
+	for i, tc := range testCases {
+		s := fraud.NewScorer(tc.signals...)
+		if a := s.Assess(receipt("4.50", time.Time{}, "4.50", "Gatorade")); a.Outcome != tc.outcome {
+			t.Errorf("Wrong outcome in test case %d: expected %s, received %s (score %.2f)", i+1, tc.outcome, a.Outcome, a.Score)
+		}
+	}
+}
+
+func TestScorer_Assess_DefaultSignals(t *testing.T) {
+	type testCase struct {
+		receipt *model.Receipt
+		outcome fraud.Outcome
+	}
+
+	bonus := time.Date(2025, 1, 22, 15, 0, 0, 0, time.UTC)
+	ordinary := time.Date(2025, 1, 21, 13, 1, 0, 0, time.UTC)
+
+	var testCases = []testCase{
+		{receipt: receipt("9.00", ordinary, "2.25", "Gatorade", "Gatorade", "Gatorade", "Gatorade"), outcome: fraud.Allow},
+		{receipt: receipt("35.35", ordinary, "5.87", "Mountain Dew 12PK", "Emils Cheese Pizza", "Knorr Creamy Chicken", "Doritos Nacho Cheese", "   Klarbrunn 12-PK 12 FL OZ  "), outcome: fraud.Allow},
+		{receipt: receipt("13.50", ordinary, "2.25", repeat("Gatorade", 6)...), outcome: fraud.Flag},
+		{receipt: receipt("499.99", ordinary, "499.99", "TV"), outcome: fraud.Allow},
+		{receipt: receipt("111.00", ordinary, "111.00", "Gatorade"), outcome: fraud.Flag},
+		{receipt: receipt("9999.00", ordinary, "9999.00", "Gatorade"), outcome: fraud.Hold},
+		// the fake receipt maximizing every rule
+		{receipt: receipt("9999.00", bonus, "99.99", repeat("a", 100)...), outcome: fraud.Reject},
+	}
+
+	for i, tc := range testCases {
+		s := fraud.NewScorer(fraud.DefaultSignals()...)
+		if a := s.Assess(tc.receipt); a.Outcome != tc.outcome {
+			t.Errorf("Wrong outcome in test case %d: expected %s, received %s (%v)", i+1, tc.outcome, a.Outcome, a.Reasons())
+		}
+	}
+}
+
+func TestScorer_Screen(t *testing.T) {
+	s := fraud.NewScorer(fraud.NewVelocity(2, time.Hour))
+
+	// receipts screened at once are each counted against the next
+	var wg sync.WaitGroup
+	outcomes := make(chan fraud.Outcome, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := receipt("4.50", time.Time{}, "4.50", "Gatorade")
+			r.UserID = "alice"
+			outcomes <- s.Screen(r).Outcome
+		}()
+	}
+	wg.Wait()
+	close(outcomes)
+
+	allowed := 0
+	for outcome := range outcomes {
+		if outcome == fraud.Allow {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("Expected 2 of the receipts screened at once to be allowed, received %d", allowed)
+	}
+
+	// rejected receipts aren't learned from
+	s = fraud.NewScorer(fixed(2), fraud.NewVelocity(1, time.Hour))
+	r := receipt("4.50", time.Time{}, "4.50", "Gatorade")
+	r.UserID = "bob"
+	for i := range 3 {
+		if a := s.Screen(r); a.Score != 2 {
+			t.Errorf("Expected rejected receipt %d not to count toward the velocity limit, received score %.2f", i+1, a.Score)
+		}
+	}
+}
+
+func TestScorer_Flagged(t *testing.T) {
+	s := fraud.NewScorer()
+
+	s.Record("r1", fraud.Assessment{Outcome: fraud.Allow})
+	s.Record("r2", fraud.Assessment{Outcome: fraud.Flag})
+	s.Record("r3", fraud.Assessment{Outcome: fraud.Hold})
+	s.Record("", fraud.Assessment{Outcome: fraud.Reject})
+
+	type testCase struct {
+		outcome fraud.Outcome
+		ids     []string
+	}
+
+	var testCases = []testCase{
+		{outcome: "", ids: []string{"", "r3", "r2"}},
+		{outcome: fraud.Hold, ids: []string{"r3"}},
+		{outcome: fraud.Allow, ids: []string{}},
+	}
+
+	for i, tc := range testCases {
+		flagged := s.Flagged(tc.outcome)
+		ids := make([]string, 0)
+		for _, a := range flagged {
+			ids = append(ids, a.ReceiptID)
+		}
+		if len(ids) != len(tc.ids) {
+			t.Errorf("Wrong receipts listed in test case %d: expected %q, received %q", i+1, tc.ids, ids)
+			continue
+		}
+		for j := range ids {
+			if ids[j] != tc.ids[j] {
+				t.Errorf("Wrong receipts listed in test case %d: expected %q, received %q", i+1, tc.ids, ids)
+				break
+			}
+		}
+	}
+}
+
+func TestScorer_Reviewed(t *testing.T) {
+	s := fraud.NewScorer()
+	s.MaxFlagged = 2
+
+	s.Record("r1", fraud.Assessment{Outcome: fraud.Hold})
+	s.Record("r2", fraud.Assessment{Outcome: fraud.Flag})
+	s.Record("r3", fraud.Assessment{Outcome: fraud.Hold})
+
+	// the oldest assessments are dropped past the limit
+	if flagged := s.Flagged(""); len(flagged) != 2 || flagged[0].ReceiptID != "r3" || flagged[1].ReceiptID != "r2" {
+		t.Errorf("Expected r3 & r2 to be listed, received %+v", flagged)
+	}
+	// reviewed receipts are no longer listed
+	if !s.Reviewed("r3") {
+		t.Errorf("Expected r3 to have been listed for review")
+	}
+	if s.Reviewed("r3") || s.Reviewed("r1") || s.Reviewed("") {
+		t.Errorf("Expected receipts not listed for review to be reported as such")
+	}
+	if flagged := s.Flagged(""); len(flagged) != 1 || flagged[0].ReceiptID != "r2" {
+		t.Errorf("Expected only r2 to be listed, received %+v", flagged)
+	}
+}
+
+func TestScorer_Validate(t *testing.T) {
+	type testCase struct {
+		flagAt, holdAt, rejectAt float64
+		errExpected              bool
+	}
+
+	var testCases = []testCase{
+		{flagAt: 0.5, holdAt: 1, rejectAt: 2},
+		{flagAt: 1, holdAt: 1, rejectAt: 1},
+		{flagAt: 0, holdAt: 1, rejectAt: 2, errExpected: true},
+		{flagAt: 1, holdAt: 0.5, rejectAt: 2, errExpected: true},
+		{flagAt: 0.5, holdAt: 2, rejectAt: 1, errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		s := &fraud.Scorer{FlagAt: tc.flagAt, HoldAt: tc.holdAt, RejectAt: tc.rejectAt}
+		if err := s.Validate(); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error validating thresholds in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package fraud
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// Defaults for the built-in signals.
+const (
+	DefaultMinDuplicateItems = 5
+	DefaultImprobableTotal   = 5000 // in the base currency
+	DefaultVelocityMax       = 10
+	DefaultVelocityWindow    = 24 * time.Hour
+)
+
+// minPatternItems is the fewest items a pattern across a receipt's items is looked for in.
+const minPatternItems = 5
+
+// RuleMaximizing scores receipts shaped to earn every bonus the points rules pay, rather than to record a purchase:
+// items with descriptions too short to name a product, & a round total at the bonus day & hour.
+type RuleMaximizing struct{}
+
+func (s *RuleMaximizing) Name() string { return "rule-maximizing" }
+
+func (s *RuleMaximizing) Score(r *model.Receipt) (score float64, reason string) {
+	reasons := make([]string, 0)
+
+	// patterns are only looked for across enough items, so short receipts of real products like "TV" aren't held
+	if len(r.Items) >= minPatternItems {
+		// meaningless descriptions, e.g. "a", are the cheapest way to pad out a receipt
+		meaningless := 0
+		for _, item := range r.Items {
+			if alphanumerics(item.ShortDescription) < 3 {
+				meaningless++
+			}
+		}
+		if meaningless > 0 {
+			score += float64(meaningless) / float64(len(r.Items))
+			reasons = append(reasons, fmt.Sprintf("%d of %d items have no meaningful description", meaningless, len(r.Items)))
+		}
+
+		// every description measuring a multiple of 3 earns the item bonus on each item
+		multiples := 0
+		for _, item := range r.Items {
+			if utf8.RuneCountInString(strings.TrimSpace(item.ShortDescription))%3 == 0 {
+				multiples++
+			}
+		}
+		if multiples == len(r.Items) {
+			score += 0.3
+			reasons = append(reasons, "every item description earns the item bonus")
+		}
+	}
+
+	// legitimate receipts hit all three of these often enough that they only count together
+	local := r.PurchasedAt
+	round := strings.HasSuffix(r.Total, ".00")
+	if round && local.Day()%2 == 0 && local.Hour() == 15 {
+		score += 0.4
+		reasons = append(reasons, "round total at the bonus day & hour")
+	}
+	return score, strings.Join(reasons, "; ")
+}
+
+// DuplicateItems scores receipts listing the same item at the same price over & over.
+type DuplicateItems struct {
+	MinItems int // Receipts with fewer items aren't scored.
+}
+
+func (s *DuplicateItems) Name() string { return "duplicate-items" }
+
+func (s *DuplicateItems) Score(r *model.Receipt) (score float64, reason string) {
+	if len(r.Items) < s.MinItems {
+		return 0, ""
+	}
+	seen := make(map[string]bool)
+	duplicates := 0
+	for _, item := range r.Items {
+		key := model.NormalizeDescription(item.ShortDescription) + "\x00" + item.Price
+		if seen[key] {
+			duplicates++
+		}
+		seen[key] = true
+	}
+	if duplicates == 0 {
+		return 0, ""
+	}
+	return float64(duplicates) / float64(len(r.Items)), fmt.Sprintf("%d of %d items are repeats", duplicates, len(r.Items))
+}
+
+// ImprobableTotal scores totals few real purchases add up to: very large totals,
+// & those made of a single repeated digit, e.g. 9999.00 or 111.11. Real purchases do sometimes
+// add up to a repeated digit, so those are only enough to flag a receipt on their own.
+type ImprobableTotal struct {
+	Max float64 // Totals above this, in the base currency, are improbable.
+}
+
+func (s *ImprobableTotal) Name() string { return "improbable-total" }
+
+func (s *ImprobableTotal) Score(r *model.Receipt) (score float64, reason string) {
+	total, err := strconv.ParseFloat(r.Total, 64)
+	if err != nil {
+		return 0, ""
+	}
+	rate := r.ExchangeRate
+	if rate == 0 {
+		rate = 1
+	}
+
+	if s.Max > 0 && total*rate > s.Max {
+		return 1, fmt.Sprintf("total of %.2f in the base currency exceeds %.2f", total*rate, s.Max)
+	}
+	whole, minor, _ := strings.Cut(r.Total, ".")
+	if len(whole) >= 3 && repeated(whole) && (strings.Trim(minor, "0") == "" || repeated(whole+minor)) {
+		return 0.5, fmt.Sprintf("total %s repeats a single digit", r.Total)
+	}
+	return 0, ""
+}
+
+// Velocity scores users submitting more receipts within a window of time than a shopper plausibly would.
+type Velocity struct {
+	Max         int              // Receipts a user may submit within the window.
+	Window      time.Duration    // The trailing window submissions are counted over.
+	Now         func() time.Time // Clock submissions are timed by; defaults to time.Now.
+	submissions map[string][]time.Time
+	sync.Mutex
+}
+
+// NewVelocity returns a Velocity signal allowing max receipts per user within window.
+func NewVelocity(max int, window time.Duration) *Velocity {
+	return &Velocity{Max: max, Window: window, submissions: make(map[string][]time.Time)}
+}
+
+func (s *Velocity) Name() string { return "velocity" }
+
+// Score grows by one for each Max receipts beyond the limit, starting from half for the first receipt over it.
+func (s *Velocity) Score(r *model.Receipt) (score float64, reason string) {
+	if r.UserID == "" || s.Max <= 0 {
+		return 0, ""
+	}
+	s.Lock()
+	defer s.Unlock()
+	count := len(s.recent(r.UserID)) + 1
+	if count <= s.Max {
+		return 0, ""
+	}
+	return 0.5 + float64(count-s.Max-1)/float64(s.Max), fmt.Sprintf("receipt %d from this user within %s", count, s.Window)
+}
+
+// Record counts a receipt which wasn't rejected against its user.
+func (s *Velocity) Record(r *model.Receipt) {
+	if r.UserID == "" {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.submissions[r.UserID] = append(s.recent(r.UserID), s.now())
+}
+
+// recent returns, & keeps only, a user's submissions within the window.
+func (s *Velocity) recent(userID string) []time.Time {
+	since := s.now().Add(-s.Window)
+	kept := s.submissions[userID][:0]
+	for _, at := range s.submissions[userID] {
+		if at.After(since) {
+			kept = append(kept, at)
+		}
+	}
+	if len(kept) == 0 {
+		delete(s.submissions, userID)
+		return nil
+	}
+	s.submissions[userID] = kept
+	return kept
+}
+
+// now returns the current time, as seen by the signal's clock.
+func (s *Velocity) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+// alphanumerics counts the letters & digits in a string.
+func alphanumerics(s string) (n int) {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// repeated reports whether a string is one character, repeated.
+func repeated(s string) bool {
+	for i := 1; i < len(s); i++ {
+		if s[i] != s[0] {
+			return false
+		}
+	}
+	return true
+}
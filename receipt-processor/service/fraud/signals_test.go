@@ -0,0 +1,111 @@
+package fraud_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/fraud"
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// receipt builds a receipt purchased at an instant, with an item for each description, each at the same price.
+func receipt(total string, at time.Time, price string, descriptions ...string) *model.Receipt {
+	r := &model.Receipt{Retailer: "Target", Total: total, PurchasedAt: at, ExchangeRate: 1, Items: make([]*model.Item, 0)}
+	for _, d := range descriptions {
+		r.Items = append(r.Items, &model.Item{ShortDescription: d, Price: price})
+	}
+	return r
+}
+
+// repeat returns n copies of a description.
+func repeat(description string, n int) (descriptions []string) {
+	for range n {
+		descriptions = append(descriptions, description)
+	}
+	return descriptions
+}
+
+func TestSignals_Score(t *testing.T) {
+	type testCase struct {
+		signal  fraud.Signal
+		receipt *model.Receipt
+		score   float64
+	}
+
+	// an even day, during the bonus hour
+	bonus := time.Date(2025, 1, 22, 15, 30, 0, 0, time.UTC)
+	ordinary := time.Date(2025, 1, 21, 11, 0, 0, 0, time.UTC)
+
+	var testCases = []testCase{
+		// rule-maximizing
+		{signal: &fraud.RuleMaximizing{}, receipt: receipt("9.00", ordinary, "2.25", "Gatorade", "Doritos", "Pepsi", "Bananas"), score: 0},
+		{signal: &fraud.RuleMaximizing{}, receipt: receipt("9.00", bonus, "2.25", "Gatorade", "Doritos", "Pepsi", "Bananas"), score: 0.4},
+		{signal: &fraud.RuleMaximizing{}, receipt: receipt("11.25", ordinary, "2.25", "a", "b", "Gatorade", "Doritos", "Bananas"), score: 0.4},
+		// too few items to look for a pattern in
+		{signal: &fraud.RuleMaximizing{}, receipt: receipt("4.50", ordinary, "2.25", "a", "Gatorade"), score: 0},
+		{signal: &fraud.RuleMaximizing{}, receipt: receipt("499.99", ordinary, "499.99", "TV"), score: 0},
+		// 100 items of "a", with a round total at the bonus day & hour
+		{signal: &fraud.RuleMaximizing{}, receipt: receipt("9999.00", bonus, "99.99", repeat("a", 100)...), score: 1.4},
+		{signal: &fraud.RuleMaximizing{}, receipt: receipt("10.00", ordinary, "2.00", repeat("abc", 5)...), score: 0.3},
+		// duplicate items
+		{signal: &fraud.DuplicateItems{MinItems: 5}, receipt: receipt("9.00", ordinary, "2.25", repeat("Gatorade", 4)...), score: 0},
+		{signal: &fraud.DuplicateItems{MinItems: 5}, receipt: receipt("11.25", ordinary, "2.25", repeat("Gatorade", 5)...), score: 0.8},
+		{signal: &fraud.DuplicateItems{MinItems: 5}, receipt: receipt("11.25", ordinary, "2.25", "Gatorade", " GATORADE", "Pepsi", "Doritos", "Bananas"), score: 0.2},
+		// improbable totals
+		{signal: &fraud.ImprobableTotal{Max: 5000}, receipt: receipt("35.35", ordinary, "35.35", "Gatorade"), score: 0},
+		{signal: &fraud.ImprobableTotal{Max: 5000}, receipt: receipt("9999.00", ordinary, "9999.00", "Gatorade"), score: 1},
+		{signal: &fraud.ImprobableTotal{Max: 5000}, receipt: receipt("5000.01", ordinary, "5000.01", "Gatorade"), score: 1},
+		{signal: &fraud.ImprobableTotal{Max: 5000}, receipt: receipt("111.11", ordinary, "111.11", "Gatorade"), score: 0.5},
+		{signal: &fraud.ImprobableTotal{Max: 5000}, receipt: receipt("111.00", ordinary, "111.00", "Gatorade"), score: 0.5},
+		{signal: &fraud.ImprobableTotal{Max: 5000}, receipt: receipt("222.50", ordinary, "222.50", "Gatorade"), score: 0},
+		{signal: &fraud.ImprobableTotal{Max: 5000}, receipt: receipt("99.00", ordinary, "99.00", "Gatorade"), score: 0},
+	}
+
+	for i, tc := range testCases {
+		if score, _ := tc.signal.Score(tc.receipt); fmt.Sprintf("%.2f", score) != fmt.Sprintf("%.2f", tc.score) {
+			t.Errorf("Wrong %s score in test case %d: expected %.2f, received %.2f", tc.signal.Name(), i+1, tc.score, score)
+		}
+	}
+}
+
+func TestVelocity_Score(t *testing.T) {
+	c := &clock{now: time.Date(2025, 1, 21, 0, 0, 0, 0, time.UTC)}
+	v := fraud.NewVelocity(2, time.Hour)
+	v.Now = c.Now
+
+	r := receipt("4.50", c.now, "4.50", "Gatorade")
+	r.UserID = "alice"
+
+	type testCase struct {
+		advance time.Duration
+		score   float64
+	}
+
+	var testCases = []testCase{
+		{score: 0},
+		{score: 0},
+		// the third receipt within the hour is over the limit, & each further one more so
+		{score: 0.5},
+		{score: 1},
+		// earlier receipts fall out of the window
+		{advance: 61 * time.Minute, score: 0},
+	}
+
+	for i, tc := range testCases {
+		c.now = c.now.Add(tc.advance)
+		if score, _ := v.Score(r); score != tc.score {
+			t.Errorf("Wrong velocity score in test case %d: expected %.2f, received %.2f", i+1, tc.score, score)
+		}
+		v.Record(r)
+	}
+
+	// receipts without a user aren't counted
+	anonymous := receipt("4.50", c.now, "4.50", "Gatorade")
+	for range 5 {
+		v.Record(anonymous)
+	}
+	if score, _ := v.Score(anonymous); score != 0 {
+		t.Errorf("Expected anonymous receipts to score 0, received %.2f", score)
+	}
+}
@@ -0,0 +1,228 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// maxPartDepth bounds how deeply nested multipart bodies are walked.
+const maxPartDepth = 8
+
+// body is a decoded leaf part of a MIME message.
+type body struct {
+	mediaType string
+	text      string
+}
+
+// ParseEmail reads a raw RFC 822 message, walks its MIME tree for a text or HTML body,
+// and extracts a Receipt using the template registered for the message's sender.
+func ParseEmail(raw []byte) (receipt *pb.Receipt, err error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return &pb.Receipt{}, model.ErrBadRequest("Email could not be read: " + err.Error())
+	}
+
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return &pb.Receipt{}, model.ErrBadRequest("Email has no valid From address")
+	}
+
+	tmpl, ok := templateFor(from[0].Address)
+	if !ok {
+		return &pb.Receipt{}, model.ErrBadRequest("No e-receipt template is registered for sender: " + from[0].Address)
+	}
+
+	bodies := make([]body, 0)
+	if err := walkPart(msg.Header, msg.Body, 0, &bodies); err != nil {
+		return &pb.Receipt{}, err
+	}
+
+	// prefer the plain text alternative, falling back to rendered HTML
+	var text string
+	for _, b := range bodies {
+		if b.mediaType == "text/plain" {
+			text = b.text
+			break
+		}
+	}
+	if text == "" {
+		for _, b := range bodies {
+			if b.mediaType == "text/html" {
+				if text, err = htmlToText(b.text); err != nil {
+					return &pb.Receipt{}, model.ErrBadRequest("Email HTML body could not be read: " + err.Error())
+				}
+				break
+			}
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return &pb.Receipt{}, model.ErrBadRequest("Email contains no text or HTML body")
+	}
+
+	return tmpl.extract(text)
+}
+
+// header is the subset of a MIME header needed to decode a part.
+type header interface {
+	Get(key string) string
+}
+
+// walkPart recursively decodes a MIME part, collecting every text/plain & text/html leaf.
+// Attachments are skipped.
+func walkPart(h header, r io.Reader, depth int, bodies *[]body) (err error) {
+	if depth > maxPartDepth {
+		return model.ErrBadRequest("Email MIME structure is nested too deeply")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045 - a missing or malformed Content-Type defaults to plain text
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return model.ErrBadRequest("Email multipart body could not be read: " + err.Error())
+			}
+			if err := walkPart(part.Header, part, depth+1, bodies); err != nil {
+				return err
+			}
+		}
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return nil
+	}
+	if disposition, _, _ := mime.ParseMediaType(h.Get("Content-Disposition")); disposition == "attachment" {
+		return nil
+	}
+
+	decoded, err := decodeTransfer(h.Get("Content-Transfer-Encoding"), r)
+	if err != nil {
+		return model.ErrBadRequest("Email body could not be decoded: " + err.Error())
+	}
+	text, err := decodeCharset(params["charset"], decoded)
+	if err != nil {
+		return err
+	}
+
+	*bodies = append(*bodies, body{mediaType: mediaType, text: text})
+	return nil
+}
+
+// decodeTransfer reverses a part's Content-Transfer-Encoding.
+func decodeTransfer(encoding string, r io.Reader) (decoded []byte, err error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(r))
+	case "base64":
+		return io.ReadAll(base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: r}))
+	default:
+		return io.ReadAll(r)
+	}
+}
+
+// decodeCharset converts a part body to UTF-8.
+func decodeCharset(charset string, b []byte) (text string, err error) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "us-ascii":
+		return string(b), nil
+	case "iso-8859-1", "latin1":
+		// Latin-1 code points map directly onto the first 256 runes
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes), nil
+	default:
+		return "", model.ErrBadRequest(fmt.Sprintf("Email body charset %s is not supported", charset))
+	}
+}
+
+// newlineStripper drops CR & LF bytes, which base64 bodies wrap at 76 columns.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		read, err := n.r.Read(p)
+		kept := 0
+		for _, c := range p[:read] {
+			if c != '\r' && c != '\n' {
+				p[kept] = c
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+var whitespace_regexp = regexp.MustCompile(`\s+`)
+
+// blockElements start a new line of text when rendered.
+var blockElements = map[string]bool{
+	"br": true, "p": true, "div": true, "tr": true, "li": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// htmlToText renders an HTML body as lines of text, one per block element,
+// separating table cells with a tab so templates can match them as columns.
+func htmlToText(doc string) (text string, err error) {
+	var sb strings.Builder
+	z := html.NewTokenizer(strings.NewReader(doc))
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return sb.String(), nil
+			}
+			return "", z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch tag := string(name); {
+			case tag == "script" || tag == "style":
+				skip++
+			case tag == "td" || tag == "th":
+				sb.WriteByte('\t')
+			case blockElements[tag]:
+				sb.WriteByte('\n')
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch tag := string(name); {
+			case tag == "script" || tag == "style":
+				if skip > 0 {
+					skip--
+				}
+			case blockElements[tag]:
+				sb.WriteByte('\n')
+			}
+		case html.TextToken:
+			if skip == 0 {
+				sb.WriteString(whitespace_regexp.ReplaceAllString(string(z.Text()), " "))
+			}
+		}
+	}
+}
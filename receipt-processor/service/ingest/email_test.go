@@ -0,0 +1,40 @@
+package ingest_test
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/ingest"
+)
+
+func Test_ParseEmail(t *testing.T) {
+	golden[*pb.Receipt]{
+		dir:   "email",
+		ext:   ".eml",
+		parse: ingest.ParseEmail,
+		decode: func(expected []byte) (*pb.Receipt, error) {
+			want := &pb.Receipt{}
+			return want, protojson.Unmarshal(expected, want)
+		},
+		equal: func(received *pb.Receipt, expected *pb.Receipt) bool { return proto.Equal(received, expected) },
+	}.run(t)
+}
+
+func Test_ParseEmail_Malformed(t *testing.T) {
+	type testCase = string
+
+	var testCases = []testCase{
+		"",
+		"not an email at all",
+		"From: Target <orders@target.com>\r\nContent-Type: multipart/alternative; boundary=\"x\"\r\n\r\n--x\r\nContent-Type: image/png\r\n\r\nPNG\r\n--x--\r\n",
+		"From: Target <orders@target.com>\r\nContent-Type: text/plain; charset=shift_jis\r\n\r\nOrder date: January 2, 2022\r\n",
+	}
+	for i, tc := range testCases {
+		if _, err := ingest.ParseEmail([]byte(tc)); err == nil {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+}
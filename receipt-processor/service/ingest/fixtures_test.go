@@ -0,0 +1,58 @@
+package ingest_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// golden checks a parser against every fixture in a testdata directory.
+// A fixture with a matching .json file must produce the receipt it decodes to; a fixture without one must be rejected.
+type golden[R any] struct {
+	dir    string                            // The directory under testdata holding the fixtures.
+	ext    string                            // The extension of the fixtures, e.g. ".xml".
+	parse  func(raw []byte) (R, error)       // Parses a fixture.
+	decode func(expected []byte) (R, error)  // Decodes a fixture's expected receipt.
+	equal  func(received R, expected R) bool // Reports whether a parsed receipt is the one expected.
+}
+
+func (g golden[R]) run(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", g.dir, "*"+g.ext))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("No %s fixtures were found: %v", g.dir, err)
+	}
+
+	for _, fixture := range fixtures {
+		name := filepath.Base(fixture)
+		raw, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatalf("Error reading fixture %s: %v", name, err)
+		}
+
+		expected, err := os.ReadFile(strings.TrimSuffix(fixture, g.ext) + ".json")
+		errExpected := os.IsNotExist(err)
+		if err != nil && !errExpected {
+			t.Fatalf("Error reading expected receipt for fixture %s: %v", name, err)
+		}
+
+		receipt, err := g.parse(raw)
+		if err != nil && !errExpected {
+			t.Errorf("Unexpected error parsing fixture %s: %v", name, err)
+			continue
+		} else if err == nil && errExpected {
+			t.Errorf("Did not receive expected error parsing fixture %s", name)
+			continue
+		} else if errExpected {
+			continue
+		}
+
+		want, err := g.decode(expected)
+		if err != nil {
+			t.Fatalf("Error unmarshaling expected receipt for fixture %s: %v", name, err)
+		}
+		if !g.equal(receipt, want) {
+			t.Errorf("Receipt parsed from fixture %s is not identical to expected receipt: expected %+v, received %+v", name, want, receipt)
+		}
+	}
+}
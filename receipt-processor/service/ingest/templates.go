@@ -0,0 +1,129 @@
+package ingest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// A Template describes how to read the e-receipt emails sent by a single retailer.
+// Each pattern is matched against one trimmed line of the email body at a time.
+type Template struct {
+	Retailer   string         // The retailer name set on extracted receipts.
+	Senders    []string       // Sender domains whose emails this template reads.
+	Date       *regexp.Regexp // First submatch is the purchase date.
+	DateLayout string         // time.Parse layout for the purchase date submatch.
+	Time       *regexp.Regexp // First submatch is the purchase time.
+	TimeLayout string         // time.Parse layout for the purchase time submatch.
+	Item       *regexp.Regexp // Submatches are the item's short description & price.
+	Total      *regexp.Regexp // First submatch is the total amount paid.
+	Skip       *regexp.Regexp // Lines which resemble items but are not, e.g. subtotals & tax.
+}
+
+// amount captures a dollar amount, with or without a leading currency symbol.
+const amount = `\$?\s*(\d+(?:,\d{3})*\.\d{2})`
+
+// Templates are the retailer-specific e-receipt templates, matched by sender domain.
+var Templates = []*Template{
+	{
+		Retailer:   "Target",
+		Senders:    []string{"target.com", "oe.target.com"},
+		Date:       regexp.MustCompile(`^Order date:\s*(.+)$`),
+		DateLayout: "January 2, 2006",
+		Time:       regexp.MustCompile(`^Order time:\s*(.+)$`),
+		TimeLayout: "3:04 PM",
+		Item:       regexp.MustCompile(`^(.+?)\s+` + amount + `$`),
+		Total:      regexp.MustCompile(`^Total:?\s+` + amount + `$`),
+		Skip:       regexp.MustCompile(`^(Subtotal|Estimated tax|Tax|Delivery)\b`),
+	},
+	{
+		Retailer:   "Walgreens",
+		Senders:    []string{"walgreens.com", "email.walgreens.com"},
+		Date:       regexp.MustCompile(`^Purchase Date\s+(\d{2}/\d{2}/\d{4})$`),
+		DateLayout: "01/02/2006",
+		Time:       regexp.MustCompile(`^Purchase Time\s+(\d{2}:\d{2})$`),
+		TimeLayout: "15:04",
+		Item:       regexp.MustCompile(`^(.+?)\t+` + amount + `$`),
+		Total:      regexp.MustCompile(`^TOTAL\s+` + amount + `$`),
+		Skip:       regexp.MustCompile(`^(SUBTOTAL|TAX|Purchase)\b`),
+	},
+}
+
+// templateFor returns the template registered for a sender address' domain.
+func templateFor(address string) (tmpl *Template, ok bool) {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return nil, false
+	}
+	domain := strings.ToLower(address[at+1:])
+	for _, t := range Templates {
+		for _, sender := range t.Senders {
+			if domain == sender {
+				return t, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// extract reads a receipt out of the text body of an email.
+func (t *Template) extract(text string) (receipt *pb.Receipt, err error) {
+	receipt = &pb.Receipt{Retailer: t.Retailer, Items: make([]*pb.Item, 0)}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.ReplaceAll(line, "\r", ""))
+		if line == "" {
+			continue
+		}
+
+		if m := t.Total.FindStringSubmatch(line); m != nil {
+			receipt.Total = trimAmount(m[1])
+		} else if m := t.Date.FindStringSubmatch(line); m != nil {
+			if d, err := time.Parse(t.DateLayout, strings.TrimSpace(m[1])); err != nil {
+				return &pb.Receipt{}, model.ErrBadRequest(fmt.Sprintf("Email purchase date %q could not be read", m[1]))
+			} else {
+				receipt.PurchaseDate = d.Format(time.DateOnly)
+			}
+		} else if m := t.Time.FindStringSubmatch(line); m != nil {
+			if d, err := time.Parse(t.TimeLayout, strings.TrimSpace(m[1])); err != nil {
+				return &pb.Receipt{}, model.ErrBadRequest(fmt.Sprintf("Email purchase time %q could not be read", m[1]))
+			} else {
+				receipt.PurchaseTime = d.Format("15:04")
+			}
+		} else if t.Skip != nil && t.Skip.MatchString(line) {
+			continue
+		} else if m := t.Item.FindStringSubmatch(line); m != nil {
+			receipt.Items = append(receipt.Items, &pb.Item{
+				ShortDescription: strings.TrimSpace(m[1]),
+				Price:            trimAmount(m[2]),
+			})
+		}
+	}
+
+	missing := make([]string, 0)
+	if receipt.PurchaseDate == "" {
+		missing = append(missing, "purchaseDate")
+	}
+	if receipt.PurchaseTime == "" {
+		missing = append(missing, "purchaseTime")
+	}
+	if len(receipt.Items) == 0 {
+		missing = append(missing, "items")
+	}
+	if receipt.Total == "" {
+		missing = append(missing, "total")
+	}
+	if len(missing) > 0 {
+		return &pb.Receipt{}, model.ErrBadRequest(fmt.Sprintf("%s e-receipt is missing fields %s", t.Retailer, missing))
+	}
+	return receipt, nil
+}
+
+// trimAmount strips thousands separators from an amount.
+func trimAmount(a string) string {
+	return strings.ReplaceAll(a, ",", "")
+}
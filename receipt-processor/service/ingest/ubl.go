@@ -0,0 +1,169 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// ublInvoiceNS is the UBL 2.1 Invoice document namespace.
+// Child elements are matched by local name, regardless of their cac/cbc namespace prefix.
+const ublInvoiceNS = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+
+// ublInvoice is the subset of a UBL 2.1 Invoice that maps onto a Receipt.
+type ublInvoice struct {
+	XMLName      xml.Name
+	IssueDate    string `xml:"IssueDate"`
+	IssueTime    string `xml:"IssueTime"`
+	CurrencyCode string `xml:"DocumentCurrencyCode"`
+	Supplier     struct {
+		Name             string `xml:"Party>PartyName>Name"`
+		RegistrationName string `xml:"Party>PartyLegalEntity>RegistrationName"`
+	} `xml:"AccountingSupplierParty"`
+	PayableAmount ublAmount        `xml:"LegalMonetaryTotal>PayableAmount"`
+	Lines         []ublInvoiceLine `xml:"InvoiceLine"`
+}
+
+// ublAmount is a UBL monetary amount, an xsd:decimal with the currency as an attribute.
+type ublAmount struct {
+	Value      string `xml:",chardata"`
+	CurrencyID string `xml:"currencyID,attr"`
+}
+
+// ublInvoiceLine is the subset of a UBL 2.1 InvoiceLine that maps onto an Item.
+type ublInvoiceLine struct {
+	LineExtensionAmount ublAmount `xml:"LineExtensionAmount"`
+	Item                struct {
+		Name        string `xml:"Name"`
+		Description string `xml:"Description"`
+	} `xml:"Item"`
+}
+
+// DecodeUBLInvoice reads a UBL 2.1 Invoice, mapping the supplier name, issue date & time, document currency,
+// invoice line extension amounts and payable amount onto a Receipt.
+// The Receipt is not validated; see model.ProcessReceipt.
+func DecodeUBLInvoice(r io.Reader) (receipt *model.Receipt, err error) {
+	var inv ublInvoice
+	if err := xml.NewDecoder(r).Decode(&inv); err != nil {
+		return &model.Receipt{}, model.ErrBadRequest("UBL invoice could not be read: " + err.Error())
+	} else if inv.XMLName.Space != ublInvoiceNS || inv.XMLName.Local != "Invoice" {
+		return &model.Receipt{}, model.ErrBadRequest(fmt.Sprintf("Document is not a UBL 2.1 Invoice: found root element %s %s", inv.XMLName.Space, inv.XMLName.Local))
+	}
+
+	receipt = &model.Receipt{
+		Retailer: strings.TrimSpace(inv.Supplier.Name),
+		Date:     strings.TrimSpace(inv.IssueDate),
+		Items:    make([]*model.Item, 0),
+	}
+	if receipt.Retailer == "" {
+		receipt.Retailer = strings.TrimSpace(inv.Supplier.RegistrationName)
+	}
+
+	// IssueTime is an xsd:time, hh:mm:ss with optional fractional seconds & zone
+	if issued := strings.TrimSpace(inv.IssueTime); issued != "" {
+		for _, layout := range []string{"15:04:05Z07:00", "15:04:05.999999999Z07:00", "15:04:05", "15:04:05.999999999"} {
+			if t, err := time.Parse(layout, issued); err == nil {
+				receipt.Time = t.Format(model.CanonicalTime)
+				if strings.HasSuffix(layout, "Z07:00") {
+					receipt.TimeZone = t.Format("Z07:00")
+				}
+				break
+			}
+		}
+		if receipt.Time == "" {
+			return &model.Receipt{}, model.ErrBadRequest(fmt.Sprintf("UBL invoice IssueTime %q is invalid", issued))
+		}
+	}
+
+	// the document currency applies to every amount, falling back to the payable amount's own
+	receipt.Currency = strings.TrimSpace(inv.CurrencyCode)
+	if receipt.Currency == "" {
+		receipt.Currency = strings.TrimSpace(inv.PayableAmount.CurrencyID)
+	}
+	currency, ok := model.LookupCurrency(receipt.Currency)
+	if !ok {
+		return &model.Receipt{}, model.ErrBadRequest(fmt.Sprintf("UBL invoice currency %s is not supported", receipt.Currency))
+	}
+
+	if receipt.Total, err = formatAmount(inv.PayableAmount.Value, currency); err != nil {
+		return &model.Receipt{}, model.ErrBadRequest("UBL invoice PayableAmount is invalid: " + err.Error())
+	}
+
+	for i, line := range inv.Lines {
+		desc := strings.TrimSpace(line.Item.Name)
+		if desc == "" {
+			desc = strings.TrimSpace(line.Item.Description)
+		}
+		price, err := formatAmount(line.LineExtensionAmount.Value, currency)
+		if err != nil {
+			return &model.Receipt{}, model.ErrBadRequest(fmt.Sprintf("UBL invoice line %d LineExtensionAmount is invalid: %s", i+1, err.Error()))
+		}
+		receipt.Items = append(receipt.Items, &model.Item{ShortDescription: desc, Price: price})
+	}
+
+	return receipt, nil
+}
+
+// formatAmount converts a UBL xsd:decimal amount to the receipt format,
+// with exactly as many decimals as the currency's minor unit.
+// Amounts with more significant decimals than that are rejected rather than rounded.
+func formatAmount(a string, currency model.Currency) (amount string, err error) {
+	a = strings.TrimSpace(a)
+	if a == "" {
+		return "", fmt.Errorf("amount is missing")
+	}
+	whole, frac, _ := strings.Cut(a, ".")
+	frac = strings.TrimRight(frac, "0")
+	if whole == "" || len(frac) > currency.Exponent || strings.Trim(whole, "0123456789") != "" || strings.Trim(frac, "0123456789") != "" {
+		return "", fmt.Errorf("%q is not a valid %s amount", a, currency.Code)
+	}
+	if currency.Exponent == 0 {
+		return whole, nil
+	}
+	return whole + "." + (frac + strings.Repeat("0", currency.Exponent))[:currency.Exponent], nil
+}
+
+// UBLMarshaler lets the gRPC-Gateway accept UBL 2.1 invoices as `application/xml` request bodies for ProcessReceipt.
+// Responses are still written as JSON.
+type UBLMarshaler struct {
+	runtime.JSONPb
+}
+
+// Unmarshal decodes a UBL invoice into a ProcessReceiptRequest.
+func (m *UBLMarshaler) Unmarshal(data []byte, v interface{}) error {
+	return m.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// NewDecoder returns a Decoder which reads a UBL invoice into a ProcessReceiptRequest.
+func (m *UBLMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error {
+		req, ok := v.(*pb.ProcessReceiptRequest)
+		if !ok {
+			return model.ErrBadRequest("application/xml is only accepted when processing receipts")
+		}
+		receipt, err := DecodeUBLInvoice(r)
+		if err != nil {
+			return err
+		}
+
+		req.Retailer = receipt.Retailer
+		req.PurchaseDate = receipt.Date
+		req.PurchaseTime = receipt.Time
+		req.Total = receipt.Total
+		req.TimeZone = receipt.TimeZone
+		req.Currency = receipt.Currency
+		req.Items = make([]*pb.Item, 0, len(receipt.Items))
+		for _, item := range receipt.Items {
+			req.Items = append(req.Items, &pb.Item{ShortDescription: item.ShortDescription, Price: item.Price})
+		}
+		return nil
+	})
+}
@@ -0,0 +1,75 @@
+package ingest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/ingest"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/rates"
+)
+
+func Test_DecodeUBLInvoice(t *testing.T) {
+	golden[*model.Receipt]{
+		dir: "ubl",
+		ext: ".xml",
+		parse: func(raw []byte) (*model.Receipt, error) {
+			return ingest.DecodeUBLInvoice(bytes.NewReader(raw))
+		},
+		decode: func(expected []byte) (*model.Receipt, error) {
+			want := &model.Receipt{}
+			return want, json.Unmarshal(expected, want)
+		},
+		equal: func(received *model.Receipt, expected *model.Receipt) bool {
+			return reflect.DeepEqual(received, expected)
+		},
+	}.run(t)
+}
+
+func TestUBLMarshaler_NewDecoder(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "ubl", "peppol-invoice.xml"))
+	if err != nil {
+		t.Fatalf("Error reading fixture: %v", err)
+	}
+	defer f.Close()
+
+	m := &ingest.UBLMarshaler{}
+	req := &pb.ProcessReceiptRequest{}
+	if err := m.NewDecoder(f).Decode(req); err != nil {
+		t.Fatalf("Error decoding UBL invoice into ProcessReceiptRequest: %v", err)
+	}
+
+	// a decoded invoice must be accepted by receipt processing as-is, in the invoice's currency
+	table := rates.NewTable("USD")
+	if err := table.Add("EUR", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), 1.13); err != nil {
+		t.Fatalf("Unexpected error adding exchange rate: %v", err)
+	}
+	p := model.NewProcessor()
+	p.Rates = table
+	r := &pb.Receipt{
+		Retailer:     req.Retailer,
+		PurchaseDate: req.PurchaseDate,
+		PurchaseTime: req.PurchaseTime,
+		Items:        req.Items,
+		Total:        req.Total,
+		TimeZone:     req.TimeZone,
+		Currency:     req.Currency,
+	}
+	if rec, err := p.ProcessReceipt(context.Background(), r); err != nil {
+		t.Errorf("Receipt decoded from UBL invoice was not processed: %v", err)
+	} else if rec.Currency != "EUR" {
+		t.Errorf("Receipt decoded from UBL invoice was processed in %s, not EUR", rec.Currency)
+	}
+
+	if err := m.NewDecoder(strings.NewReader("")).Decode(&pb.AwardPointsRequest{}); err == nil {
+		t.Error("Expected BadRequest error was not encountered decoding XML into an unsupported request")
+	}
+}
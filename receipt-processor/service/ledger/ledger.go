@@ -0,0 +1,261 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors returned by postings which would break the ledger's invariants.
+var (
+	ErrAlreadyAwarded      = errors.New("points were already awarded for receipt")
+	ErrAlreadyReversed     = errors.New("entry was already reversed")
+	ErrEntryNotFound       = errors.New("ledger entry not found")
+	ErrIdempotencyConflict = errors.New("idempotency key was already used for a different request")
+	ErrInsufficientBalance = errors.New("insufficient points balance")
+	ErrInvalidReason       = errors.New("reason code is not valid")
+	ErrInvalidRequest      = errors.New("invalid ledger request")
+)
+
+// A Kind describes why points were posted to a user's account.
+type Kind string
+
+const (
+	Award    Kind = "AWARD"    // Points awarded for a processed receipt.
+	Redeem   Kind = "REDEEM"   // Points spent by the user.
+	Reversal Kind = "REVERSAL" // Points clawed back or refunded, undoing an earlier entry.
+	Expiry   Kind = "EXPIRY"   // Points which went unspent until their lot expired.
+)
+
+// A Reason explains why points were redeemed or reversed.
+type Reason string
+
+// Reasons points may be redeemed for.
+const (
+	Reward   Reason = "REWARD"
+	GiftCard Reason = "GIFT_CARD"
+	Donation Reason = "DONATION"
+)
+
+// Reasons an entry may be reversed for.
+const (
+	InvalidReceipt      Reason = "INVALID_RECEIPT"
+	DuplicateReceipt    Reason = "DUPLICATE_RECEIPT"
+	Fraud               Reason = "FRAUD"
+	CancelledRedemption Reason = "CANCELLED_REDEMPTION"
+	PostingError        Reason = "POSTING_ERROR"
+)
+
+// Reasons are the reason codes accepted for each kind of entry.
+var Reasons = map[Kind][]Reason{
+	Redeem:   {Reward, GiftCard, Donation},
+	Reversal: {InvalidReceipt, DuplicateReceipt, Fraud, CancelledRedemption, PostingError},
+}
+
+// An Entry is an immutable record of points posted to a user's account.
+type Entry struct {
+	ID        string
+	UserID    string
+	ReceiptID string // The receipt the points were posted for, if any.
+	Kind      Kind
+	Points    int64 // Credits are positive & debits negative.
+	Balance   int64 // The user's running balance, including this entry.
+	PostedAt  time.Time
+	Reason    Reason // Why the points were redeemed or reversed.
+	Reverses  string // The id of the entry this entry reverses, if any.
+	key       string // The idempotency key the entry was posted with, if any.
+}
+
+// A Ledger records every posting of points, by user, in the order they were posted.
+// Entries are never modified or removed; balances are the sum of a user's entries.
+type Ledger struct {
+	Now          func() time.Time // Clock entries are dated by; defaults to time.Now.
+	ExpiryMonths int              // Months after being awarded that points expire; zero if they never do.
+	Logger       *slog.Logger     // Where scheduled expiries are logged; defaults to slog's default logger.
+	entries      map[string][]Entry
+	byID         map[string]Entry
+	awarded      map[string]Entry        // award entries, by receipt id
+	reversed     map[string]string       // reversal entry ids, by the id of the entry they reverse
+	keys         map[string]Entry        // entries posted with an idempotency key, by user & key
+	lots         map[string][]*Lot       // unexpired points, by user
+	lotFor       map[string]*Lot         // lots, by the id of the award entry that created them
+	drawn        map[string][]allocation // points drawn from lots, by the id of the entry that drew them
+	sync.RWMutex
+}
+
+// New returns an empty Ledger, expiring points after DefaultExpiryMonths.
+func New() *Ledger {
+	return &Ledger{
+		ExpiryMonths: DefaultExpiryMonths,
+		entries:      make(map[string][]Entry),
+		byID:         make(map[string]Entry),
+		awarded:      make(map[string]Entry),
+		reversed:     make(map[string]string),
+		keys:         make(map[string]Entry),
+		lots:         make(map[string][]*Lot),
+		lotFor:       make(map[string]*Lot),
+		drawn:        make(map[string][]allocation),
+	}
+}
+
+// Award credits a user with the points awarded for a receipt.
+// Each receipt may only be awarded once; later awards return ErrAlreadyAwarded & the original entry.
+func (l *Ledger) Award(userID string, receiptID string, points int64) (entry Entry, err error) {
+	l.Lock()
+	defer l.Unlock()
+	if e, exists := l.awarded[receiptID]; exists {
+		return e, ErrAlreadyAwarded
+	}
+	entry = l.post(Entry{UserID: userID, ReceiptID: receiptID, Kind: Award, Points: points})
+	l.awarded[receiptID] = entry
+	l.addLot(entry)
+	return entry, nil
+}
+
+// Redeem debits points from a user's balance, which must cover them.
+// Retrying with the same idempotency key returns the original entry without debiting again.
+func (l *Ledger) Redeem(userID string, points int64, key string, reason Reason) (entry Entry, err error) {
+	if userID == "" || key == "" || points <= 0 {
+		return Entry{}, fmt.Errorf("%w: a user, idempotency key & positive points are required", ErrInvalidRequest)
+	} else if !slices.Contains(Reasons[Redeem], reason) {
+		return Entry{}, fmt.Errorf("%w: %q is not a redemption reason", ErrInvalidReason, reason)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if e, exists := l.keys[idempotencyKey(userID, key)]; exists {
+		if e.Kind != Redeem || e.Points != -points || e.Reason != reason {
+			return Entry{}, ErrIdempotencyConflict
+		}
+		return e, nil
+	}
+	if balance := l.balance(userID); balance < points {
+		return Entry{}, fmt.Errorf("%w: balance of %d cannot cover %d points", ErrInsufficientBalance, balance, points)
+	}
+	entry = l.post(Entry{UserID: userID, Kind: Redeem, Points: -points, Reason: reason, key: key})
+	l.drawn[entry.ID] = l.draw(entry, nil)
+	return entry, nil
+}
+
+// Reverse posts an entry undoing an earlier award or redemption: an award is debited back, a redemption credited back.
+// An award may be reversed after its points were spent, leaving a negative balance that later awards pay down.
+// Retrying with the same idempotency key returns the original reversal without posting again.
+func (l *Ledger) Reverse(entryID string, key string, reason Reason) (entry Entry, err error) {
+	if entryID == "" || key == "" {
+		return Entry{}, fmt.Errorf("%w: an entry & idempotency key are required", ErrInvalidRequest)
+	} else if !slices.Contains(Reasons[Reversal], reason) {
+		return Entry{}, fmt.Errorf("%w: %q is not a reversal reason", ErrInvalidReason, reason)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	original, exists := l.byID[entryID]
+	if !exists {
+		return Entry{}, fmt.Errorf("%w: %s", ErrEntryNotFound, entryID)
+	}
+	if e, exists := l.keys[idempotencyKey(original.UserID, key)]; exists {
+		if e.Kind != Reversal || e.Reverses != entryID || e.Reason != reason {
+			return Entry{}, ErrIdempotencyConflict
+		}
+		return e, nil
+	}
+	if original.Kind != Award && original.Kind != Redeem {
+		return Entry{}, fmt.Errorf("%w: only awards & redemptions can be reversed", ErrInvalidRequest)
+	} else if _, exists := l.reversed[entryID]; exists {
+		return Entry{}, fmt.Errorf("%w: %s", ErrAlreadyReversed, entryID)
+	}
+	// an award's expired points are already gone, so only what's left of it or was spent from it is clawed back
+	points := original.Points
+	if lot, exists := l.lotFor[original.ID]; exists && original.Kind == Award {
+		points -= lot.Expired
+	}
+	entry = l.post(Entry{
+		UserID:    original.UserID,
+		ReceiptID: original.ReceiptID,
+		Kind:      Reversal,
+		Points:    -points,
+		Reason:    reason,
+		Reverses:  entryID,
+		key:       key,
+	})
+	l.reversed[entryID] = entry.ID
+	// a clawed back award is drawn from its own lot first; a cancelled redemption is refunded to the lots it drew from
+	if original.Kind == Award {
+		l.draw(entry, l.lotFor[original.ID])
+	} else {
+		l.refund(entry, l.drawn[original.ID])
+	}
+	return entry, nil
+}
+
+// AwardFor returns the entry awarding points for a receipt, if the receipt was awarded to a user.
+func (l *Ledger) AwardFor(receiptID string) (entry Entry, err error) {
+	l.RLock()
+	defer l.RUnlock()
+	if e, exists := l.awarded[receiptID]; !exists {
+		return Entry{}, fmt.Errorf("%w: no award for receipt %s", ErrEntryNotFound, receiptID)
+	} else {
+		return e, nil
+	}
+}
+
+// Balance returns the sum of a user's entries; users without entries have a balance of zero.
+func (l *Ledger) Balance(userID string) (balance int64) {
+	l.RLock()
+	defer l.RUnlock()
+	return l.balance(userID)
+}
+
+// Entries returns a copy of a user's entries, oldest first.
+func (l *Ledger) Entries(userID string) (entries []Entry) {
+	l.RLock()
+	defer l.RUnlock()
+	entries = make([]Entry, len(l.entries[userID]))
+	copy(entries, l.entries[userID])
+	return entries
+}
+
+// post dates & appends an entry to its user's account; the caller must hold the lock.
+func (l *Ledger) post(e Entry) Entry {
+	e.ID = uuid.New().String()
+	e.PostedAt = l.now()
+	e.Balance = l.balance(e.UserID) + e.Points
+	l.entries[e.UserID] = append(l.entries[e.UserID], e)
+	l.byID[e.ID] = e
+	if e.key != "" {
+		l.keys[idempotencyKey(e.UserID, e.key)] = e
+	}
+	return e
+}
+
+// idempotencyKey scopes an idempotency key to its user, so users can't collide with each other's keys.
+func idempotencyKey(userID string, key string) string {
+	return userID + "\x00" + key
+}
+
+// balance returns a user's running balance; the caller must hold the lock.
+func (l *Ledger) balance(userID string) int64 {
+	if entries := l.entries[userID]; len(entries) > 0 {
+		return entries[len(entries)-1].Balance
+	}
+	return 0
+}
+
+func (l *Ledger) now() time.Time {
+	if l.Now == nil {
+		return time.Now()
+	}
+	return l.Now()
+}
+
+func (l *Ledger) logger() *slog.Logger {
+	if l.Logger == nil {
+		return slog.Default()
+	}
+	return l.Logger
+}
@@ -0,0 +1,214 @@
+package ledger_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/ledger"
+)
+
+func TestLedger_Award(t *testing.T) {
+	type testCase struct {
+		userID      string
+		receiptID   string
+		points      int64
+		wantBalance int64
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{userID: "alice", receiptID: "r1", points: 100, wantBalance: 100},
+		{userID: "alice", receiptID: "r2", points: 28, wantBalance: 128},
+		{userID: "bob", receiptID: "r3", points: 5, wantBalance: 5},
+		// a receipt can only be awarded once, even to another user
+		{userID: "alice", receiptID: "r1", points: 100, wantBalance: 128, errExpected: true},
+		{userID: "bob", receiptID: "r2", points: 28, wantBalance: 5, errExpected: true},
+		// zero-point awards are still recorded
+		{userID: "bob", receiptID: "r4", points: 0, wantBalance: 5},
+	}
+
+	l := ledger.New()
+	for i, tc := range testCases {
+		_, err := l.Award(tc.userID, tc.receiptID, tc.points)
+		if err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error awarding points in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		} else if err != nil && !errors.Is(err, ledger.ErrAlreadyAwarded) {
+			t.Errorf("Wrong error in test case %d: %v", i+1, err)
+		}
+		if balance := l.Balance(tc.userID); balance != tc.wantBalance {
+			t.Errorf("Wrong balance in test case %d: expected %d, received %d", i+1, tc.wantBalance, balance)
+		}
+	}
+}
+
+func TestLedger_Entries(t *testing.T) {
+	posted := time.Date(2025, 1, 21, 13, 43, 0, 0, time.UTC)
+	l := ledger.New()
+	l.Now = func() time.Time { return posted }
+
+	for i, points := range []int64{10, 20, 30} {
+		if _, err := l.Award("alice", string(rune('a'+i)), points); err != nil {
+			t.Fatalf("Unexpected error awarding points: %v", err)
+		}
+	}
+
+	entries := l.Entries("alice")
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, received %d", len(entries))
+	}
+	for i, want := range []int64{10, 30, 60} {
+		if entries[i].Balance != want {
+			t.Errorf("Wrong running balance for entry %d: expected %d, received %d", i+1, want, entries[i].Balance)
+		}
+		if entries[i].Kind != ledger.Award || !entries[i].PostedAt.Equal(posted) || entries[i].ID == "" {
+			t.Errorf("Entry %d was not recorded as a dated award: %+v", i+1, entries[i])
+		}
+	}
+
+	// entries are copies, so callers can't rewrite history
+	entries[0].Points = 1000
+	if l.Entries("alice")[0].Points != 10 || l.Balance("alice") != 60 {
+		t.Errorf("Ledger entries were modified through a returned copy")
+	}
+
+	if entries := l.Entries("nobody"); len(entries) != 0 {
+		t.Errorf("Expected no entries for an unknown user, received %d", len(entries))
+	}
+}
+
+func TestLedger_Award_Concurrent(t *testing.T) {
+	l := ledger.New()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Award("alice", "r1", 100)
+		}()
+	}
+	wg.Wait()
+
+	if balance := l.Balance("alice"); balance != 100 {
+		t.Errorf("Receipt was awarded more than once: expected balance 100, received %d", balance)
+	}
+}
+
+func TestLedger_Redeem(t *testing.T) {
+	type testCase struct {
+		userID      string
+		points      int64
+		key         string
+		reason      ledger.Reason
+		wantBalance int64
+		wantErr     error
+	}
+
+	var testCases = []testCase{
+		{userID: "alice", points: 40, key: "k1", reason: ledger.Reward, wantBalance: 60},
+		// retries with the same key don't debit twice
+		{userID: "alice", points: 40, key: "k1", reason: ledger.Reward, wantBalance: 60},
+		// but a key can't be reused for a different redemption
+		{userID: "alice", points: 10, key: "k1", reason: ledger.Reward, wantBalance: 60, wantErr: ledger.ErrIdempotencyConflict},
+		{userID: "alice", points: 61, key: "k2", reason: ledger.GiftCard, wantBalance: 60, wantErr: ledger.ErrInsufficientBalance},
+		{userID: "alice", points: 60, key: "k2", reason: ledger.GiftCard, wantBalance: 0},
+		// keys are scoped to their user
+		{userID: "bob", points: 1, key: "k1", reason: ledger.Donation, wantBalance: 0, wantErr: ledger.ErrInsufficientBalance},
+		{userID: "alice", points: 1, key: "k3", reason: ledger.Fraud, wantBalance: 0, wantErr: ledger.ErrInvalidReason},
+		{userID: "alice", points: 0, key: "k3", reason: ledger.Reward, wantBalance: 0, wantErr: ledger.ErrInvalidRequest},
+		{userID: "alice", points: 1, key: "", reason: ledger.Reward, wantBalance: 0, wantErr: ledger.ErrInvalidRequest},
+	}
+
+	l := ledger.New()
+	if _, err := l.Award("alice", "r1", 100); err != nil {
+		t.Fatalf("Unexpected error awarding points: %v", err)
+	}
+	for i, tc := range testCases {
+		_, err := l.Redeem(tc.userID, tc.points, tc.key, tc.reason)
+		if err != nil && tc.wantErr == nil {
+			t.Errorf("Unexpected error redeeming points in test case %d: %v", i+1, err)
+		} else if err == nil && tc.wantErr != nil {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		} else if err != nil && !errors.Is(err, tc.wantErr) {
+			t.Errorf("Wrong error in test case %d: expected %v, received %v", i+1, tc.wantErr, err)
+		}
+		if balance := l.Balance(tc.userID); balance != tc.wantBalance {
+			t.Errorf("Wrong balance in test case %d: expected %d, received %d", i+1, tc.wantBalance, balance)
+		}
+	}
+}
+
+func TestLedger_Reverse(t *testing.T) {
+	l := ledger.New()
+	award, _ := l.Award("alice", "r1", 100)
+	redemption, err := l.Redeem("alice", 30, "k1", ledger.Reward)
+	if err != nil {
+		t.Fatalf("Unexpected error redeeming points: %v", err)
+	}
+
+	type testCase struct {
+		entryID     string
+		key         string
+		reason      ledger.Reason
+		wantBalance int64
+		wantErr     error
+	}
+
+	var testCases = []testCase{
+		// a cancelled redemption is credited back
+		{entryID: redemption.ID, key: "k2", reason: ledger.CancelledRedemption, wantBalance: 100},
+		{entryID: redemption.ID, key: "k2", reason: ledger.CancelledRedemption, wantBalance: 100},
+		{entryID: redemption.ID, key: "k3", reason: ledger.CancelledRedemption, wantBalance: 100, wantErr: ledger.ErrAlreadyReversed},
+		{entryID: award.ID, key: "k2", reason: ledger.InvalidReceipt, wantBalance: 100, wantErr: ledger.ErrIdempotencyConflict},
+		{entryID: award.ID, key: "k4", reason: ledger.Reward, wantBalance: 100, wantErr: ledger.ErrInvalidReason},
+		{entryID: "missing", key: "k4", reason: ledger.Fraud, wantBalance: 100, wantErr: ledger.ErrEntryNotFound},
+		// an invalid receipt's award is clawed back
+		{entryID: award.ID, key: "k4", reason: ledger.InvalidReceipt, wantBalance: 0},
+	}
+
+	for i, tc := range testCases {
+		_, err := l.Reverse(tc.entryID, tc.key, tc.reason)
+		if err != nil && tc.wantErr == nil {
+			t.Errorf("Unexpected error reversing entry in test case %d: %v", i+1, err)
+		} else if err == nil && tc.wantErr != nil {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		} else if err != nil && !errors.Is(err, tc.wantErr) {
+			t.Errorf("Wrong error in test case %d: expected %v, received %v", i+1, tc.wantErr, err)
+		}
+		if balance := l.Balance("alice"); balance != tc.wantBalance {
+			t.Errorf("Wrong balance in test case %d: expected %d, received %d", i+1, tc.wantBalance, balance)
+		}
+	}
+
+	// reversals can't be reversed, and are linked to the entry they undo
+	entries := l.Entries("alice")
+	last := entries[len(entries)-1]
+	if last.Kind != ledger.Reversal || last.Reverses != award.ID || last.Points != -100 || last.ReceiptID != "r1" {
+		t.Errorf("Award reversal was not recorded against the award: %+v", last)
+	}
+	if _, err := l.Reverse(last.ID, "k5", ledger.PostingError); !errors.Is(err, ledger.ErrInvalidRequest) {
+		t.Errorf("Expected reversing a reversal to fail, received %v", err)
+	}
+}
+
+func TestLedger_Reverse_SpentAward(t *testing.T) {
+	l := ledger.New()
+	award, _ := l.Award("alice", "r1", 100)
+	if _, err := l.Redeem("alice", 80, "k1", ledger.GiftCard); err != nil {
+		t.Fatalf("Unexpected error redeeming points: %v", err)
+	}
+
+	// clawing back spent points leaves a debt, which blocks redemptions until later awards pay it down
+	if _, err := l.Reverse(award.ID, "k2", ledger.Fraud); err != nil {
+		t.Fatalf("Unexpected error reversing award: %v", err)
+	}
+	if balance := l.Balance("alice"); balance != -80 {
+		t.Errorf("Wrong balance after reversing a spent award: expected -80, received %d", balance)
+	}
+	if _, err := l.Redeem("alice", 1, "k3", ledger.Reward); !errors.Is(err, ledger.ErrInsufficientBalance) {
+		t.Errorf("Expected redemption from a negative balance to fail, received %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package ledger
+
+import (
+	ctx "context"
+	"slices"
+	"time"
+)
+
+// DefaultExpiryMonths is how long awarded points last before they expire.
+const DefaultExpiryMonths = 12
+
+// A Lot is the points awarded for a single receipt which haven't yet been spent, clawed back or expired.
+// A user's lots always hold their balance, or nothing while their balance is negative.
+type Lot struct {
+	EntryID   string // The award entry the lot was created by.
+	UserID    string
+	ReceiptID string
+	Points    int64 // The points remaining in the lot.
+	Expired   int64 // The points which expired from the lot.
+	AwardedAt time.Time
+	ExpiresAt time.Time // When the remaining points expire; zero if they never do.
+}
+
+// allocation records points drawn from a lot, so they can be returned if the posting is reversed.
+type allocation struct {
+	lot    *Lot
+	points int64
+}
+
+// expiresAt returns when points awarded at a time expire.
+func (l *Ledger) expiresAt(awarded time.Time) time.Time {
+	if l.ExpiryMonths <= 0 {
+		return time.Time{}
+	}
+	return awarded.AddDate(0, l.ExpiryMonths, 0)
+}
+
+// pooled returns how many points a balance holds in lots; a negative balance is a debt, holding none.
+func pooled(balance int64) int64 {
+	return max(balance, 0)
+}
+
+// change returns how many points an entry added to (or, when negative, removed from) its user's lots.
+func change(e Entry) int64 {
+	return pooled(e.Balance) - pooled(e.Balance-e.Points)
+}
+
+// addLot creates the lot for an award entry, holding whatever isn't needed to pay down a debt; the caller must hold the lock.
+func (l *Ledger) addLot(e Entry) {
+	if points := change(e); points > 0 {
+		lot := &Lot{
+			EntryID:   e.ID,
+			UserID:    e.UserID,
+			ReceiptID: e.ReceiptID,
+			Points:    points,
+			AwardedAt: e.PostedAt,
+			ExpiresAt: l.expiresAt(e.PostedAt),
+		}
+		l.lots[e.UserID] = append(l.lots[e.UserID], lot)
+		l.lotFor[e.ID] = lot
+		// points are drawn from the soonest expiring lots, with lots that never expire last
+		slices.SortStableFunc(l.lots[e.UserID], func(a, b *Lot) int {
+			if a.ExpiresAt.IsZero() || b.ExpiresAt.IsZero() {
+				return boolCompare(a.ExpiresAt.IsZero(), b.ExpiresAt.IsZero())
+			}
+			return a.ExpiresAt.Compare(b.ExpiresAt)
+		})
+	}
+}
+
+func boolCompare(a, b bool) int {
+	if a == b {
+		return 0
+	} else if a {
+		return 1
+	}
+	return -1
+}
+
+// draw removes an entry's points from its user's lots, starting with first (if any) & then the soonest expiring;
+// the caller must hold the lock.
+func (l *Ledger) draw(e Entry, first *Lot) (drawn []allocation) {
+	points := -change(e)
+	take := func(lot *Lot) {
+		if n := min(points, lot.Points); n > 0 {
+			lot.Points -= n
+			points -= n
+			drawn = append(drawn, allocation{lot, n})
+		}
+	}
+	if first != nil {
+		take(first)
+	}
+	for _, lot := range l.lots[e.UserID] {
+		take(lot)
+	}
+	return drawn
+}
+
+// refund returns a reversed entry's points to the lots they were drawn from; the caller must hold the lock.
+func (l *Ledger) refund(e Entry, drawn []allocation) {
+	points := change(e)
+	for i := len(drawn) - 1; i >= 0 && points > 0; i-- {
+		n := min(points, drawn[i].points)
+		drawn[i].lot.Points += n
+		points -= n
+	}
+}
+
+// Expire posts an expiry entry for each lot past its expiry date, debiting its remaining points.
+func (l *Ledger) Expire(now time.Time) (expired []Entry) {
+	l.Lock()
+	defer l.Unlock()
+	expired = make([]Entry, 0)
+	for _, lots := range l.lots {
+		for _, lot := range lots {
+			if lot.Points > 0 && !lot.ExpiresAt.IsZero() && !now.Before(lot.ExpiresAt) {
+				e := l.post(Entry{UserID: lot.UserID, ReceiptID: lot.ReceiptID, Kind: Expiry, Points: -lot.Points})
+				lot.Expired += lot.Points
+				lot.Points = 0
+				expired = append(expired, e)
+			}
+		}
+	}
+	return expired
+}
+
+// RunExpiry expires lots every interval, until the context is cancelled.
+func (l *Ledger) RunExpiry(c ctx.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+			if expired := l.Expire(l.now()); len(expired) > 0 {
+				l.logger().InfoContext(c, "Expired points lots", "lots", len(expired))
+			}
+		}
+	}
+}
+
+// Expiring returns copies of the lots with points expiring before a time, soonest first.
+// Lots are limited to the user's, the receipts', or both; at least one must be given.
+func (l *Ledger) Expiring(userID string, receiptIDs []string, before time.Time) (lots []Lot) {
+	l.RLock()
+	defer l.RUnlock()
+
+	candidates := l.lots[userID]
+	if len(receiptIDs) > 0 {
+		candidates = make([]*Lot, 0)
+		for _, id := range receiptIDs {
+			lot, exists := l.lotFor[l.awarded[id].ID]
+			if exists && (userID == "" || lot.UserID == userID) && !slices.Contains(candidates, lot) {
+				candidates = append(candidates, lot)
+			}
+		}
+	}
+
+	lots = make([]Lot, 0)
+	for _, lot := range candidates {
+		if lot.Points > 0 && !lot.ExpiresAt.IsZero() && lot.ExpiresAt.Before(before) {
+			lots = append(lots, *lot)
+		}
+	}
+	slices.SortStableFunc(lots, func(a, b Lot) int { return a.ExpiresAt.Compare(b.ExpiresAt) })
+	return lots
+}
@@ -0,0 +1,163 @@
+package ledger_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/ledger"
+)
+
+// clock is a settable ledger clock.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// lotPoints sums the points held in lots.
+func lotPoints(lots []ledger.Lot) (points int64) {
+	for _, lot := range lots {
+		points += lot.Points
+	}
+	return points
+}
+
+func TestLedger_Expire(t *testing.T) {
+	c := &clock{now: date(2025, 1, 1)}
+	l := ledger.New()
+	l.Now = c.Now
+
+	l.Award("alice", "r1", 100)
+	c.now = date(2025, 3, 1)
+	l.Award("alice", "r2", 50)
+
+	// r1's points expire 12 months after they were awarded
+	if lots := l.Expiring("alice", nil, date(2026, 1, 15)); len(lots) != 1 || lots[0].ReceiptID != "r1" || !lots[0].ExpiresAt.Equal(date(2026, 1, 1)) {
+		t.Errorf("Expected r1's lot to expire on 2026-01-01, received %+v", lots)
+	}
+
+	// redemptions spend the soonest expiring points first
+	if _, err := l.Redeem("alice", 120, "k1", ledger.Reward); err != nil {
+		t.Fatalf("Unexpected error redeeming points: %v", err)
+	}
+	if lots := l.Expiring("alice", nil, date(2026, 3, 15)); len(lots) != 1 || lots[0].ReceiptID != "r2" || lots[0].Points != 30 {
+		t.Errorf("Expected 30 points left in r2's lot, received %+v", lots)
+	}
+
+	// nothing has expired yet
+	if expired := l.Expire(date(2025, 12, 31)); len(expired) != 0 {
+		t.Errorf("Expected no lots to expire yet, received %+v", expired)
+	}
+	expired := l.Expire(date(2026, 3, 1))
+	if len(expired) != 1 || expired[0].Kind != ledger.Expiry || expired[0].Points != -30 || expired[0].ReceiptID != "r2" {
+		t.Errorf("Expected r2's remaining 30 points to expire, received %+v", expired)
+	}
+	if balance := l.Balance("alice"); balance != 0 {
+		t.Errorf("Expected a balance of 0 after expiry, received %d", balance)
+	}
+	// lots only expire once
+	if expired := l.Expire(date(2026, 3, 2)); len(expired) != 0 {
+		t.Errorf("Expected no further lots to expire, received %+v", expired)
+	}
+}
+
+func TestLedger_Expiring(t *testing.T) {
+	c := &clock{now: date(2025, 1, 1)}
+	l := ledger.New()
+	l.Now = c.Now
+
+	l.Award("alice", "r1", 100)
+	c.now = date(2025, 2, 1)
+	l.Award("alice", "r2", 50)
+	l.Award("bob", "r3", 25)
+
+	type testCase struct {
+		userID     string
+		receiptIDs []string
+		before     time.Time
+		wantPoints int64
+	}
+
+	var testCases = []testCase{
+		{userID: "alice", before: date(2026, 3, 1), wantPoints: 150},
+		{userID: "alice", before: date(2026, 1, 15), wantPoints: 100},
+		{userID: "alice", before: date(2025, 6, 1), wantPoints: 0},
+		// a receipt set may span users, unless a user is also given
+		{receiptIDs: []string{"r1", "r3", "r3", "missing"}, before: date(2026, 3, 1), wantPoints: 125},
+		{userID: "alice", receiptIDs: []string{"r1", "r3"}, before: date(2026, 3, 1), wantPoints: 100},
+		{userID: "nobody", before: date(2026, 3, 1), wantPoints: 0},
+	}
+
+	for i, tc := range testCases {
+		if points := lotPoints(l.Expiring(tc.userID, tc.receiptIDs, tc.before)); points != tc.wantPoints {
+			t.Errorf("Wrong expiring points in test case %d: expected %d, received %d", i+1, tc.wantPoints, points)
+		}
+	}
+}
+
+func TestLedger_Lots_Reversals(t *testing.T) {
+	far := date(2100, 1, 1)
+	l := ledger.New()
+
+	award, _ := l.Award("alice", "r1", 100)
+	redemption, _ := l.Redeem("alice", 40, "k1", ledger.Reward)
+
+	// a cancelled redemption refunds the lots it drew from
+	l.Reverse(redemption.ID, "k2", ledger.CancelledRedemption)
+	if points := lotPoints(l.Expiring("alice", nil, far)); points != 100 {
+		t.Errorf("Expected 100 points back in lots, received %d", points)
+	}
+
+	// clawing back a spent award leaves a debt, with nothing left to expire
+	l.Redeem("alice", 80, "k3", ledger.GiftCard)
+	l.Reverse(award.ID, "k4", ledger.Fraud)
+	if points := lotPoints(l.Expiring("alice", nil, far)); points != 0 || l.Balance("alice") != -80 {
+		t.Errorf("Expected no points in lots & a balance of -80, received %d & %d", points, l.Balance("alice"))
+	}
+
+	// the next award pays down the debt before any of it can expire
+	l.Award("alice", "r2", 100)
+	if points := lotPoints(l.Expiring("alice", nil, far)); points != 20 || l.Balance("alice") != 20 {
+		t.Errorf("Expected 20 points in lots & a balance of 20, received %d & %d", points, l.Balance("alice"))
+	}
+}
+
+func TestLedger_Expire_Never(t *testing.T) {
+	l := ledger.New()
+	l.ExpiryMonths = 0
+	l.Award("alice", "r1", 100)
+
+	if expired := l.Expire(date(2100, 1, 1)); len(expired) != 0 || l.Balance("alice") != 100 {
+		t.Errorf("Expected points to never expire, received %+v", expired)
+	}
+}
+
+func TestLedger_Reverse_ExpiredAward(t *testing.T) {
+	c := &clock{now: date(2025, 1, 1)}
+	l := ledger.New()
+	l.Now = c.Now
+
+	// expired points are already gone, so reversing the award takes nothing more
+	award, _ := l.Award("alice", "r1", 100)
+	l.Expire(date(2026, 1, 1))
+	reversal, err := l.Reverse(award.ID, "k1", ledger.Fraud)
+	if err != nil {
+		t.Fatalf("Unexpected error reversing award: %v", err)
+	} else if reversal.Points != 0 || l.Balance("alice") != 0 {
+		t.Errorf("Expected nothing clawed back & a balance of 0, received %d & %d", reversal.Points, l.Balance("alice"))
+	}
+	l.Award("alice", "r2", 50)
+	if balance := l.Balance("alice"); balance != 50 {
+		t.Errorf("Expected a balance of 50 after the next award, received %d", balance)
+	}
+
+	// only the spent points of a partly expired award are clawed back
+	award, _ = l.Award("bob", "r3", 100)
+	l.Redeem("bob", 60, "k2", ledger.Reward)
+	l.Expire(date(2026, 1, 1))
+	if reversal, _ := l.Reverse(award.ID, "k3", ledger.Fraud); reversal.Points != -60 || l.Balance("bob") != -60 {
+		t.Errorf("Expected 60 points clawed back & a balance of -60, received %d & %d", reversal.Points, l.Balance("bob"))
+	}
+}
@@ -0,0 +1,114 @@
+package limits
+
+import (
+	"context"
+	"math"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	auth "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/auth"
+)
+
+// RETRY_AFTER_HEADER is the response metadata key (& HTTP header, through the gateway)
+// giving the seconds a limited client should wait before retrying.
+const RETRY_AFTER_HEADER = "retry-after"
+
+// FORWARDED_FOR_HEADER carries the address of the client the gateway forwarded a request for.
+const FORWARDED_FOR_HEADER = "x-forwarded-for"
+
+// UnaryInterceptor limits the RPCs each client makes, except the public RPCs such as health checks, & counts receipts
+// submitted by the submission RPCs against their daily quota; both by full method name.
+// It must run after authentication, to know who the client is.
+func (l *Limiter) UnaryInterceptor(public []string, submissions []string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		// probes mustn't be turned away as unhealthy because they, or clients sharing their address, were busy
+		if slices.Contains(public, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		client := Client(ctx)
+		if ok, retryAfter := l.Allow(client, path.Base(info.FullMethod)); !ok {
+			return nil, exhausted(ctx, retryAfter, "Rate limit exceeded")
+		}
+		if !slices.Contains(submissions, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		if ok, retryAfter := l.Submit(client); !ok {
+			return nil, exhausted(ctx, retryAfter, "Daily receipt submission quota exceeded")
+		}
+		res, err := handler(ctx, req)
+		if err != nil {
+			l.Refund(client)
+		}
+		return res, err
+	}
+}
+
+// StreamInterceptor limits the streaming RPCs each client starts, such as server reflection, except the public RPCs.
+func (l *Limiter) StreamInterceptor(public []string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if slices.Contains(public, info.FullMethod) {
+			return handler(srv, ss)
+		} else if ok, retryAfter := l.Allow(Client(ss.Context()), path.Base(info.FullMethod)); !ok {
+			return exhausted(ss.Context(), retryAfter, "Rate limit exceeded")
+		}
+		return handler(srv, ss)
+	}
+}
+
+// Client identifies who an RPC is limited as: the user a bearer token authenticated, the API key presented,
+// or else the caller's IP address. Requests the gateway forwards are limited by the address it received them from.
+func Client(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok && p.UserID != "" {
+		return "user:" + p.UserID
+	} else if ok {
+		return "key:" + p.ID
+	}
+
+	var ip net.IP
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		ip = net.ParseIP(host)
+	}
+	// the gateway dials the gRPC server locally; only then can the address it forwarded for be trusted
+	if ip != nil && ip.IsLoopback() {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if forwarded := md.Get(FORWARDED_FOR_HEADER); len(forwarded) > 0 {
+			// the gateway appends the address it received the request from to any the client sent
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if hop := net.ParseIP(strings.TrimSpace(hops[len(hops)-1])); hop != nil {
+				ip = hop
+			}
+		}
+	}
+	if ip == nil {
+		return "ip:unknown"
+	}
+	return "ip:" + ip.String()
+}
+
+// exhausted returns a RESOURCE_EXHAUSTED error, telling the client when to retry in a RETRY_AFTER_HEADER.
+func exhausted(ctx context.Context, retryAfter time.Duration, msg string) error {
+	retryAfterSeconds := seconds(retryAfter)
+	// setting the header fails only outside of an RPC, where there's no one to tell anyway
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RETRY_AFTER_HEADER, retryAfterSeconds))
+	return status.Errorf(codes.ResourceExhausted, "%s; retry after %ss", msg, retryAfterSeconds)
+}
+
+// seconds returns a wait in whole seconds, rounded up, & at least 1.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(d.Seconds()))))
+}
@@ -0,0 +1,110 @@
+package limits_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/auth"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/limits"
+)
+
+func TestClient(t *testing.T) {
+	fromPeer := func(addr string, md metadata.MD) context.Context {
+		tcp, _ := net.ResolveTCPAddr("tcp", addr)
+		return metadata.NewIncomingContext(peer.NewContext(context.Background(), &peer.Peer{Addr: tcp}), md)
+	}
+
+	type testCase struct {
+		ctx  context.Context
+		want string
+	}
+
+	var testCases = []testCase{
+		{ctx: auth.NewContext(context.Background(), auth.Principal{ID: "user:alice", UserID: "alice"}), want: "user:alice"},
+		{ctx: auth.NewContext(context.Background(), auth.Principal{ID: "mobile"}), want: "key:mobile"},
+		{ctx: fromPeer("203.0.113.7:51000", nil), want: "ip:203.0.113.7"},
+		// only the gateway, dialing locally, is trusted to say who it forwarded for
+		{ctx: fromPeer("203.0.113.7:51000", metadata.Pairs("x-forwarded-for", "198.51.100.1")), want: "ip:203.0.113.7"},
+		{ctx: fromPeer("127.0.0.1:51000", metadata.Pairs("x-forwarded-for", "198.51.100.1")), want: "ip:198.51.100.1"},
+		{ctx: fromPeer("[::1]:51000", metadata.Pairs("x-forwarded-for", "10.0.0.1, 198.51.100.1")), want: "ip:198.51.100.1"},
+		{ctx: fromPeer("127.0.0.1:51000", nil), want: "ip:127.0.0.1"},
+		{ctx: context.Background(), want: "ip:unknown"},
+	}
+
+	for i, tc := range testCases {
+		if client := limits.Client(tc.ctx); client != tc.want {
+			t.Errorf("Wrong client in test case %d: expected %q, received %q", i+1, tc.want, client)
+		}
+	}
+}
+
+// headerStream records the headers set on an RPC.
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "" }
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+func (s *headerStream) SetTrailer(md metadata.MD) error { return nil }
+
+func TestLimiter_UnaryInterceptor(t *testing.T) {
+	l, err := limits.NewLimiter(limits.Policy{
+		Methods:          map[string]limits.Limit{"Award": {PerSecond: 1, Burst: 1}, "Health": {PerSecond: 1, Burst: 1}},
+		DailySubmissions: 2,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating limiter: %v", err)
+	}
+	interceptor := l.UnaryInterceptor([]string{"/test/Health"}, []string{"/test/Process"})
+
+	type testCase struct {
+		method     string
+		fail       bool // whether the RPC itself fails
+		code       codes.Code
+		retryAfter string
+	}
+
+	var testCases = []testCase{
+		{method: "/test/Award", code: codes.OK},
+		{method: "/test/Award", code: codes.ResourceExhausted, retryAfter: "1"},
+		{method: "/test/Process", code: codes.OK},
+		// failed submissions don't count against the quota
+		{method: "/test/Process", fail: true, code: codes.InvalidArgument},
+		{method: "/test/Process", code: codes.OK},
+		{method: "/test/Process", code: codes.ResourceExhausted},
+		// public RPCs are never limited
+		{method: "/test/Health", code: codes.OK},
+		{method: "/test/Health", code: codes.OK},
+	}
+
+	for i, tc := range testCases {
+		handler := func(ctx context.Context, req any) (any, error) {
+			if tc.fail {
+				return nil, status.Error(codes.InvalidArgument, "invalid receipt")
+			}
+			return nil, nil
+		}
+		stream := &headerStream{}
+		ctx := grpc.NewContextWithServerTransportStream(auth.NewContext(context.Background(), auth.Principal{ID: "mobile"}), stream)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+		if code := status.Code(err); code != tc.code {
+			t.Errorf("Wrong status in test case %d: expected %s, received %s (%v)", i+1, tc.code, code, err)
+		} else if retryAfter := stream.header.Get("retry-after"); tc.retryAfter != "" && (len(retryAfter) == 0 || retryAfter[0] != tc.retryAfter) {
+			t.Errorf("Wrong Retry-After in test case %d: expected %s, received %v", i+1, tc.retryAfter, retryAfter)
+		} else if code == codes.ResourceExhausted && len(retryAfter) == 0 {
+			t.Errorf("No Retry-After in test case %d", i+1)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package limits
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	reload "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/reload"
+)
+
+// A Limit refills a client's bucket of requests at a steady rate, up to a burst.
+type Limit struct {
+	PerSecond float64 `json:"perSecond"` // Requests allowed each second, on average; unlimited when zero.
+	Burst     int     `json:"burst"`     // Most requests allowed at once, after a quiet spell.
+}
+
+// A Policy is the limits each client is held to.
+type Policy struct {
+	Default          Limit            `json:"default"`          // Limits RPCs without a limit of their own, together.
+	Methods          map[string]Limit `json:"methods"`          // Limits each RPC named, e.g. "ProcessReceipt", on its own.
+	DailySubmissions int              `json:"dailySubmissions"` // Most receipts a client may submit each (UTC) day; unlimited when zero.
+}
+
+// Validate reports configuration errors in the policy.
+func (p *Policy) Validate() (err error) {
+	if err := p.Default.Validate(); err != nil {
+		return fmt.Errorf("default limit %w", err)
+	}
+	for method, l := range p.Methods {
+		if err := l.Validate(); err != nil {
+			return fmt.Errorf("%s limit %w", method, err)
+		}
+	}
+	if p.DailySubmissions < 0 {
+		return fmt.Errorf("daily submissions may not be negative")
+	}
+	return nil
+}
+
+// Validate reports configuration errors in the limit.
+func (l *Limit) Validate() (err error) {
+	if l.PerSecond < 0 {
+		return fmt.Errorf("rate may not be negative")
+	} else if l.PerSecond > 0 && l.Burst < 1 {
+		return fmt.Errorf("burst must allow at least 1 request")
+	}
+	return nil
+}
+
+// PRUNE_AFTER is how long a client's buckets are kept after their last request; quotas are kept for the day they count.
+// Buckets refill well within this, so a client returning after it starts afresh, as it would have anyway.
+const PRUNE_AFTER = time.Hour
+
+// A Limiter holds clients to a Policy, read from a JSON file & reloaded when it changes,
+// so limits can be raised or lowered without a restart.
+type Limiter struct {
+	Now     func() time.Time // Clock buckets refill & quotas reset by; defaults to time.Now.
+	file    string
+	policy  Policy
+	stamps  []reload.Stamp
+	buckets map[string]*bucket // by client & method
+	quotas  map[string]*quota  // by client
+	pruned  time.Time
+	sync.Mutex
+}
+
+// bucket is a client's token bucket for an RPC (or those sharing the default limit).
+type bucket struct {
+	limit   Limit
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// quota is the receipts a client has submitted on a day.
+type quota struct {
+	day   string
+	count int
+}
+
+// NewLimiter returns a Limiter holding clients to a policy, which must be valid.
+func NewLimiter(p Policy) (l *Limiter, err error) {
+	l = &Limiter{buckets: make(map[string]*bucket), quotas: make(map[string]*quota)}
+	if err := l.SetPolicy(p); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// LoadFile reads a JSON policy.
+func LoadFile(path string) (l *Limiter, err error) {
+	l = &Limiter{file: path, buckets: make(map[string]*bucket), quotas: make(map[string]*quota)}
+	if _, err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// LoadJSON reads a JSON policy.
+func LoadJSON(r io.Reader) (l *Limiter, err error) {
+	p, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewLimiter(p)
+}
+
+// Reload re-reads the policy file if it's changed since it was last read, reporting whether it had.
+// On error the previous policy is kept.
+func (l *Limiter) Reload() (changed bool, err error) {
+	if l.file == "" {
+		return false, nil
+	}
+	stamps, err := reload.StampFiles(l.file)
+	if err != nil {
+		return false, fmt.Errorf("error reading rate limits: %w", err)
+	}
+	l.Lock()
+	unchanged := reload.Unchanged(stamps, l.stamps)
+	l.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	f, err := os.Open(l.file)
+	if err != nil {
+		return false, fmt.Errorf("error reading rate limits: %w", err)
+	}
+	defer f.Close()
+	p, err := decode(f)
+	if err != nil {
+		return false, err
+	} else if err := l.SetPolicy(p); err != nil {
+		return false, err
+	}
+	l.Lock()
+	defer l.Unlock()
+	l.stamps = stamps
+	return true, nil
+}
+
+func (l *Limiter) String() string { return l.file }
+
+// SetPolicy replaces the policy clients are held to, which must be valid.
+// Clients keep the requests left in their buckets & the receipts counted against their quotas.
+func (l *Limiter) SetPolicy(p Policy) (err error) {
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid rate limits: %w", err)
+	}
+	l.Lock()
+	defer l.Unlock()
+	l.policy = p
+	return nil
+}
+
+// Policy returns the policy clients are currently held to.
+func (l *Limiter) Policy() Policy {
+	l.Lock()
+	defer l.Unlock()
+	return l.policy
+}
+
+// Allow takes a request from a client's bucket for an RPC, by its name, returning how long the client must wait
+// before retrying when the bucket is empty.
+func (l *Limiter) Allow(client string, method string) (ok bool, retryAfter time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	now := l.now()
+	l.prune(now)
+
+	limit, own := l.policy.Methods[method]
+	key := client + " " + method
+	if !own {
+		limit, key = l.policy.Default, client
+	}
+	if limit.PerSecond == 0 {
+		return true, 0
+	}
+
+	b, exists := l.buckets[key]
+	if !exists {
+		// new buckets start full
+		b = &bucket{limit: limit, limiter: rate.NewLimiter(rate.Limit(limit.PerSecond), limit.Burst)}
+		l.buckets[key] = b
+	} else if b.limit != limit {
+		// the policy's changed; the bucket keeps what's left in it, refilling at the new rate
+		b.limiter.SetLimitAt(now, rate.Limit(limit.PerSecond))
+		b.limiter.SetBurstAt(now, limit.Burst)
+		b.limit = limit
+	}
+	b.seen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); !r.OK() || delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// Submit counts a receipt against a client's daily quota, returning how long the client must wait
+// until the quota resets when it's used up. A submission which then fails should be given back with Refund.
+func (l *Limiter) Submit(client string) (ok bool, retryAfter time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	now := l.now().UTC()
+	if l.policy.DailySubmissions == 0 {
+		return true, 0
+	}
+
+	day := now.Format(time.DateOnly)
+	q, exists := l.quotas[client]
+	if !exists || q.day != day {
+		q = &quota{day: day}
+		l.quotas[client] = q
+	}
+	if q.count >= l.policy.DailySubmissions {
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return false, midnight.Sub(now)
+	}
+	q.count++
+	return true, 0
+}
+
+// Refund gives back a submission counted by Submit, which didn't go on to store a receipt.
+func (l *Limiter) Refund(client string) {
+	l.Lock()
+	defer l.Unlock()
+	if q, exists := l.quotas[client]; exists && q.count > 0 && q.day == l.now().UTC().Format(time.DateOnly) {
+		q.count--
+	}
+}
+
+// prune forgets buckets unused for PRUNE_AFTER & past days' quotas, at most once per PRUNE_AFTER.
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < PRUNE_AFTER {
+		return
+	}
+	l.pruned = now
+	for key, b := range l.buckets {
+		if now.Sub(b.seen) >= PRUNE_AFTER {
+			delete(l.buckets, key)
+		}
+	}
+	for client, q := range l.quotas {
+		if q.day != now.UTC().Format(time.DateOnly) {
+			delete(l.quotas, client)
+		}
+	}
+}
+
+// now returns the current time, as seen by the limiter's clock.
+func (l *Limiter) now() time.Time {
+	if l.Now == nil {
+		return time.Now()
+	}
+	return l.Now()
+}
+
+func decode(r io.Reader) (p Policy, err error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return Policy{}, fmt.Errorf("error decoding rate limits: %w", err)
+	}
+	return p, nil
+}
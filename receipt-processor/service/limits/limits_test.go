@@ -0,0 +1,156 @@
+package limits_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/limits"
+)
+
+func TestLoadJSON(t *testing.T) {
+	type testCase struct {
+		json        string
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{json: `{}`},
+		{json: `{"default": {"perSecond": 10, "burst": 20}, "methods": {"ProcessReceipt": {"perSecond": 0.5, "burst": 1}}, "dailySubmissions": 100}`},
+		{json: `{"default": {"perSecond": -1, "burst": 20}}`, errExpected: true},
+		{json: `{"default": {"perSecond": 10}}`, errExpected: true},
+		{json: `{"methods": {"ProcessReceipt": {"perSecond": 1, "burst": 0}}}`, errExpected: true},
+		{json: `{"dailySubmissions": -1}`, errExpected: true},
+		{json: `{"default": {"perSecond": 10, "burst": 20}, "perMinute": 600}`, errExpected: true},
+		{json: `[]`, errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		if _, err := limits.LoadJSON(strings.NewReader(tc.json)); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error loading limits in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, err := limits.NewLimiter(limits.Policy{
+		Default: limits.Limit{PerSecond: 1, Burst: 3},
+		Methods: map[string]limits.Limit{"ProcessReceipt": {PerSecond: 0.5, Burst: 1}, "GetBalance": {}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating limiter: %v", err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	l.Now = func() time.Time { return now }
+
+	type testCase struct {
+		client     string
+		method     string
+		advance    time.Duration // before the request
+		ok         bool
+		retryAfter time.Duration
+	}
+
+	var testCases = []testCase{
+		{client: "key:mobile", method: "ProcessReceipt", ok: true},
+		{client: "key:mobile", method: "ProcessReceipt", ok: false, retryAfter: 2 * time.Second},
+		// other clients, & RPCs with their own limits, have their own buckets
+		{client: "key:partner", method: "ProcessReceipt", ok: true},
+		{client: "key:mobile", method: "AwardPoints", ok: true},
+		// RPCs without their own limit share the default bucket
+		{client: "key:mobile", method: "RedeemPoints", ok: true},
+		{client: "key:mobile", method: "AwardPoints", ok: true},
+		{client: "key:mobile", method: "RedeemPoints", ok: false, retryAfter: time.Second},
+		// RPCs with a zero limit aren't limited
+		{client: "key:mobile", method: "GetBalance", ok: true},
+		// buckets refill over time
+		{client: "key:mobile", method: "ProcessReceipt", advance: 1500 * time.Millisecond, ok: false, retryAfter: 500 * time.Millisecond},
+		{client: "key:mobile", method: "ProcessReceipt", advance: 500 * time.Millisecond, ok: true},
+		{client: "key:mobile", method: "AwardPoints", ok: true},
+		{client: "key:mobile", method: "AwardPoints", ok: true},
+	}
+
+	for i, tc := range testCases {
+		now = now.Add(tc.advance)
+		if ok, retryAfter := l.Allow(tc.client, tc.method); ok != tc.ok {
+			t.Errorf("Wrong result in test case %d: expected %t, received %t", i+1, tc.ok, ok)
+		} else if retryAfter != tc.retryAfter {
+			t.Errorf("Wrong retry time in test case %d: expected %s, received %s", i+1, tc.retryAfter, retryAfter)
+		}
+	}
+}
+
+func TestLimiter_Submit(t *testing.T) {
+	l, err := limits.NewLimiter(limits.Policy{DailySubmissions: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error creating limiter: %v", err)
+	}
+	now := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	l.Now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Submit("user:1"); !ok {
+			t.Fatalf("Submission %d was refused within the quota", i+1)
+		}
+	}
+	if ok, retryAfter := l.Submit("user:1"); ok {
+		t.Errorf("Submission past the quota was allowed")
+	} else if retryAfter != 6*time.Hour {
+		t.Errorf("Wrong retry time: expected the quota to reset in 6h, received %s", retryAfter)
+	}
+	if ok, _ := l.Submit("user:2"); !ok {
+		t.Errorf("Another client's submission was refused")
+	}
+
+	// failed submissions are given back
+	l.Refund("user:1")
+	if ok, _ := l.Submit("user:1"); !ok {
+		t.Errorf("Refunded submission was refused")
+	}
+
+	// quotas reset each day
+	now = now.Add(6 * time.Hour)
+	if ok, _ := l.Submit("user:1"); !ok {
+		t.Errorf("Submission the next day was refused")
+	}
+}
+
+func TestLimiter_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	write := func(json string, modified time.Time) {
+		if err := os.WriteFile(path, []byte(json), 0o600); err != nil {
+			t.Fatalf("Unexpected error writing limits: %v", err)
+		} else if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatalf("Unexpected error touching limits: %v", err)
+		}
+	}
+	modified := time.Now().Add(-time.Hour)
+	write(`{"dailySubmissions": 1}`, modified)
+
+	l, err := limits.LoadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error loading limits: %v", err)
+	}
+	if changed, err := l.Reload(); changed || err != nil {
+		t.Errorf("Unchanged limits were reloaded: %t, %v", changed, err)
+	}
+
+	write(`{"dailySubmissions": 5}`, modified.Add(time.Minute))
+	if changed, err := l.Reload(); !changed || err != nil {
+		t.Errorf("Changed limits weren't reloaded: %t, %v", changed, err)
+	} else if l.Policy().DailySubmissions != 5 {
+		t.Errorf("Wrong daily submissions after reload: expected 5, received %d", l.Policy().DailySubmissions)
+	}
+
+	// invalid limits are reported, & the previous ones kept
+	write(`{"dailySubmissions": -5}`, modified.Add(2*time.Minute))
+	if _, err := l.Reload(); err == nil {
+		t.Errorf("Did not receive expected error reloading invalid limits")
+	} else if l.Policy().DailySubmissions != 5 {
+		t.Errorf("Limits changed after a failed reload: received %d daily submissions", l.Policy().DailySubmissions)
+	}
+}
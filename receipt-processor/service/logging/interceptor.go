@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// REQUEST_ID_HEADER is the metadata key (& HTTP header, through the gateway) carrying the id of each request.
+// Callers may choose the id, to find their request in the logs; it's generated otherwise, & returned either way.
+const REQUEST_ID_HEADER = "x-request-id"
+
+// MAX_REQUEST_ID_LENGTH is the longest request id accepted from callers; longer ones are replaced.
+const MAX_REQUEST_ID_LENGTH = 128
+
+// UnaryInterceptor gives each RPC a request id, & logs its method, status code & latency once handled.
+// It should run before the interceptors which may reject RPCs, so they're logged too.
+func UnaryInterceptor(l *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = withRequestID(ctx)
+		start := time.Now()
+		res, err := handler(ctx, req)
+		access(ctx, l, info.FullMethod, err, time.Since(start))
+		return res, err
+	}
+}
+
+// StreamInterceptor gives each streaming RPC, such as server reflection, a request id, & logs it once it ends.
+func StreamInterceptor(l *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withRequestID(ss.Context())
+		start := time.Now()
+		err := handler(srv, &requestStream{ServerStream: ss, ctx: ctx})
+		access(ctx, l, info.FullMethod, err, time.Since(start))
+		return err
+	}
+}
+
+// requestStream is a server stream whose context carries its request id.
+type requestStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestStream) Context() context.Context {
+	return s.ctx
+}
+
+// withRequestID returns a context carrying the caller's request id, or a new one, which is returned to the caller.
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(REQUEST_ID_HEADER); len(ids) > 0 && validRequestID(ids[0]) {
+			id = ids[0]
+		}
+	}
+	if id == "" {
+		id = uuid.NewString()
+	}
+	// outside of an RPC, such as in tests, there's nowhere to return it
+	_ = grpc.SetHeader(ctx, metadata.Pairs(REQUEST_ID_HEADER, id))
+	return NewContext(ctx, id)
+}
+
+// validRequestID reports whether a caller's request id is short & printable ASCII, so it's safe to log & return.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MAX_REQUEST_ID_LENGTH {
+		return false
+	}
+	for _, c := range []byte(id) {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// access logs a handled RPC; failed RPCs are logged as warnings, or errors when the service itself failed.
+func access(ctx context.Context, l *slog.Logger, method string, err error, took time.Duration) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.DataLoss || code == codes.Unavailable {
+		level = slog.LevelError
+	} else if code != codes.OK {
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", path.Base(method)),
+		slog.String("code", code.String()),
+		slog.Duration("latency", took),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+	l.LogAttrs(ctx, level, "RPC handled", attrs...)
+}
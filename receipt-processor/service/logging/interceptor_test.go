@@ -0,0 +1,84 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/logging"
+)
+
+// headerStream records the headers set on an RPC.
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "" }
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+func (s *headerStream) SetTrailer(md metadata.MD) error { return nil }
+
+func TestUnaryInterceptor(t *testing.T) {
+	var b bytes.Buffer
+	l, err := logging.New(&b, "json", "info")
+	if err != nil {
+		t.Fatalf("Unexpected error creating logger: %v", err)
+	}
+	interceptor := logging.UnaryInterceptor(l)
+
+	type testCase struct {
+		requestID string // sent by the caller
+		err       error
+		keptID    bool // whether the caller's id is used
+		level     string
+		code      string
+	}
+
+	var testCases = []testCase{
+		{requestID: "abc-123", keptID: true, level: "INFO", code: "OK"},
+		{level: "INFO", code: "OK"},
+		// ids which aren't safe to log or return are replaced
+		{requestID: "abc 123\n", level: "INFO", code: "OK"},
+		{requestID: strings.Repeat("a", logging.MAX_REQUEST_ID_LENGTH+1), level: "INFO", code: "OK"},
+		{requestID: "abc-123", err: status.Error(codes.NotFound, "no receipt"), keptID: true, level: "WARN", code: "NotFound"},
+		{err: status.Error(codes.Internal, "store failed"), level: "ERROR", code: "Internal"},
+	}
+
+	for i, tc := range testCases {
+		b.Reset()
+		stream := &headerStream{}
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+		if tc.requestID != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(logging.REQUEST_ID_HEADER, tc.requestID))
+		}
+		var handled string
+		handler := func(ctx context.Context, req any) (any, error) {
+			handled = logging.RequestID(ctx)
+			return nil, tc.err
+		}
+		interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/receipt.ReceiptService/AwardPoints"}, handler)
+
+		var record map[string]any
+		if err := json.Unmarshal(b.Bytes(), &record); err != nil {
+			t.Fatalf("Unexpected error decoding access log in test case %d: %v", i+1, err)
+		}
+		if returned := stream.header.Get(logging.REQUEST_ID_HEADER); len(returned) != 1 || returned[0] != handled || handled == "" {
+			t.Errorf("Wrong request id returned in test case %d: handled as %q, returned %v", i+1, handled, returned)
+		} else if tc.keptID != (handled == tc.requestID) {
+			t.Errorf("Wrong request id in test case %d: sent %q, handled as %q", i+1, tc.requestID, handled)
+		} else if record["request_id"] != handled || record["method"] != "AwardPoints" || record["code"] != tc.code || record["level"] != tc.level {
+			t.Errorf("Wrong access log in test case %d: %v", i+1, record)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/logging"
+)
+
+func TestNew(t *testing.T) {
+	type testCase struct {
+		format      string
+		level       string
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{format: "text", level: "info"},
+		{format: "json", level: "debug"},
+		{format: "json", level: "warn"},
+		{format: "logfmt", level: "info", errExpected: true},
+		{format: "text", level: "verbose", errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		var b bytes.Buffer
+		if _, err := logging.New(&b, tc.format, tc.level); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error creating logger in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+}
+
+func TestNewHandler_RequestID(t *testing.T) {
+	var b bytes.Buffer
+	l, err := logging.New(&b, "json", "info")
+	if err != nil {
+		t.Fatalf("Unexpected error creating logger: %v", err)
+	}
+
+	type testCase struct {
+		ctx  context.Context
+		want string
+	}
+
+	var testCases = []testCase{
+		{ctx: logging.NewContext(context.Background(), "abc-123"), want: "abc-123"},
+		// records logged outside of a request aren't tagged
+		{ctx: context.Background(), want: ""},
+	}
+
+	for i, tc := range testCases {
+		b.Reset()
+		l.With("component", "test").InfoContext(tc.ctx, "Receipt processed")
+		var record map[string]any
+		if err := json.Unmarshal(b.Bytes(), &record); err != nil {
+			t.Fatalf("Unexpected error decoding record in test case %d: %v", i+1, err)
+		}
+		if id, _ := record["request_id"].(string); id != tc.want {
+			t.Errorf("Wrong request id in test case %d: expected %q, received %q", i+1, tc.want, id)
+		} else if record["component"] != "test" {
+			t.Errorf("Logger attributes lost in test case %d: %v", i+1, record)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// NAMESPACE prefixes the name of each metric.
+const NAMESPACE = "receipt_processor"
+
+// Metrics are the service's Prometheus metrics, in a registry of their own.
+// Their methods may be called on nil Metrics, which record nothing.
+type Metrics struct {
+	Registry           *prometheus.Registry
+	requests           *prometheus.CounterVec
+	latency            *prometheus.HistogramVec
+	stored             prometheus.Counter
+	awarded            prometheus.Counter
+	points             prometheus.Histogram
+	validationFailures *prometheus.CounterVec
+	lockWait           *prometheus.HistogramVec
+}
+
+// New returns the service's metrics, registered alongside the Go runtime's & the process's.
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "rpc_requests_total",
+			Help:      "RPCs handled, by method & status code.",
+		}, []string{"method", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "rpc_duration_seconds",
+			Help:      "Time taken to handle RPCs, by method & status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		stored: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "receipts_stored_total",
+			Help:      "Receipts validated & stored.",
+		}),
+		awarded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "receipts_awarded_total",
+			Help:      "Receipts awarded points.",
+		}),
+		points: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "points_awarded",
+			Help:      "Points awarded per receipt, including promotions.",
+			Buckets:   prometheus.ExponentialBuckets(10, 2, 12), // 10 to 20480
+		}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "validation_failures_total",
+			Help:      "Invalid fields & violated limits of rejected receipts, by field.",
+		}, []string{"field"}),
+		lockWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "datastore_lock_wait_seconds",
+			Help:      "Time receipt store operations waited for its lock, by operation.",
+			Buckets:   prometheus.ExponentialBuckets(0.000001, 4, 10), // 1µs to ~0.26s
+		}, []string{"op"}),
+	}
+	m.Registry.MustRegister(
+		m.requests, m.latency, m.stored, m.awarded, m.points, m.validationFailures, m.lockWait,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// UnaryInterceptor counts & times each RPC by its method & status code.
+// It should run before any other interceptors, so the RPCs they reject are counted too.
+func (m *Metrics) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		m.observeRPC(path.Base(info.FullMethod), err, time.Since(start))
+		return res, err
+	}
+}
+
+// StreamInterceptor counts & times each streaming RPC, such as server reflection.
+func (m *Metrics) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observeRPC(path.Base(info.FullMethod), err, time.Since(start))
+		return err
+	}
+}
+
+func (m *Metrics) observeRPC(method string, err error, took time.Duration) {
+	code := status.Code(err).String()
+	m.requests.WithLabelValues(method, code).Inc()
+	m.latency.WithLabelValues(method, code).Observe(took.Seconds())
+}
+
+// ReceiptStored counts a receipt stored.
+func (m *Metrics) ReceiptStored() {
+	if m == nil {
+		return
+	}
+	m.stored.Inc()
+}
+
+// ReceiptAwarded counts a receipt awarded points, & the points awarded.
+func (m *Metrics) ReceiptAwarded(points int64) {
+	if m == nil {
+		return
+	}
+	m.awarded.Inc()
+	m.points.Observe(float64(points))
+}
+
+// ValidationFailed counts each field a receipt was rejected for, when it failed validation.
+func (m *Metrics) ValidationFailed(err error) {
+	var verr *model.ValidationError
+	if m == nil || !errors.As(err, &verr) {
+		return
+	}
+	for _, field := range verr.Fields() {
+		m.validationFailures.WithLabelValues(field).Inc()
+	}
+}
+
+// LockWait records how long a receipt store operation waited for its lock.
+func (m *Metrics) LockWait(op string, wait time.Duration) {
+	if m == nil {
+		return
+	}
+	m.lockWait.WithLabelValues(op).Observe(wait.Seconds())
+}
@@ -0,0 +1,63 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/metrics"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+func TestMetrics_Handler(t *testing.T) {
+	m := metrics.New()
+
+	interceptor := m.UnaryInterceptor()
+	for _, err := range []error{nil, nil, status.Error(codes.NotFound, "no receipt")} {
+		handler := func(ctx context.Context, req any) (any, error) { return nil, err }
+		interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/receipt.ReceiptService/AwardPoints"}, handler)
+	}
+	m.ReceiptStored()
+	m.ReceiptAwarded(109)
+	m.ValidationFailed(&model.ValidationError{Invalid: []string{"retailer"}, Violated: []string{"items[0].shortDescription: 120 characters exceeds the maximum of 100"}})
+	m.ValidationFailed(model.ErrNotFound("not a validation failure"))
+	m.LockWait("get", time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	var want = []string{
+		`receipt_processor_rpc_requests_total{code="OK",method="AwardPoints"} 2`,
+		`receipt_processor_rpc_requests_total{code="NotFound",method="AwardPoints"} 1`,
+		`receipt_processor_rpc_duration_seconds_count{code="OK",method="AwardPoints"} 2`,
+		`receipt_processor_receipts_stored_total 1`,
+		`receipt_processor_receipts_awarded_total 1`,
+		`receipt_processor_points_awarded_bucket{le="160"} 1`,
+		`receipt_processor_points_awarded_bucket{le="80"} 0`,
+		`receipt_processor_validation_failures_total{field="retailer"} 1`,
+		`receipt_processor_validation_failures_total{field="items.shortDescription"} 1`,
+		`receipt_processor_datastore_lock_wait_seconds_count{op="get"} 1`,
+		`go_goroutines`,
+	}
+	for i, line := range want {
+		if !strings.Contains(body, line) {
+			t.Errorf("Metric %d missing: expected %q", i+1, line)
+		}
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	// services without metrics record nothing, without checking for them first
+	var m *metrics.Metrics
+	m.ReceiptStored()
+	m.ReceiptAwarded(100)
+	m.ValidationFailed(&model.ValidationError{Invalid: []string{"retailer"}})
+	m.LockWait("get", time.Millisecond)
+}
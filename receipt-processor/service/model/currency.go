@@ -0,0 +1,114 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A RateProvider supplies the exchange rates used to convert item prices
+// into the base currency points are computed in.
+type RateProvider interface {
+	// Rate returns the value of one unit of currency in the base currency on the purchase date.
+	Rate(currency string, on time.Time) (rate float64, err error)
+}
+
+// DefaultCurrency is assumed for receipts which don't specify a currency.
+const DefaultCurrency = "USD"
+
+// A Currency is an ISO 4217 currency, with the number of decimals in its minor unit.
+type Currency struct {
+	Code     string
+	Exponent int
+}
+
+// Currencies are the ISO 4217 currencies receipts may be paid in.
+var Currencies = map[string]Currency{
+	// zero decimals
+	"CLP": {"CLP", 0},
+	"ISK": {"ISK", 0},
+	"JPY": {"JPY", 0},
+	"KRW": {"KRW", 0},
+	"VND": {"VND", 0},
+	// two decimals
+	"AUD": {"AUD", 2},
+	"BRL": {"BRL", 2},
+	"CAD": {"CAD", 2},
+	"CHF": {"CHF", 2},
+	"CNY": {"CNY", 2},
+	"DKK": {"DKK", 2},
+	"EUR": {"EUR", 2},
+	"GBP": {"GBP", 2},
+	"HKD": {"HKD", 2},
+	"INR": {"INR", 2},
+	"MXN": {"MXN", 2},
+	"NOK": {"NOK", 2},
+	"NZD": {"NZD", 2},
+	"PLN": {"PLN", 2},
+	"SEK": {"SEK", 2},
+	"SGD": {"SGD", 2},
+	"USD": {"USD", 2},
+	// three decimals
+	"BHD": {"BHD", 3},
+	"JOD": {"JOD", 3},
+	"KWD": {"KWD", 3},
+	"OMR": {"OMR", 3},
+	"TND": {"TND", 3},
+}
+
+// amount_regexps match an amount with exactly as many decimals as a currency's exponent.
+var amount_regexps = map[int]*regexp.Regexp{
+	0: regexp.MustCompile(`^\d+$`),
+	2: regexp.MustCompile(`^\d+\.\d{2}$`),
+	3: regexp.MustCompile(`^\d+\.\d{3}$`),
+}
+
+// LookupCurrency returns the Currency for an ISO 4217 code, defaulting to USD when the code is empty.
+func LookupCurrency(code string) (c Currency, ok bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		code = DefaultCurrency
+	}
+	c, ok = Currencies[code]
+	return c, ok
+}
+
+// amountRegexp returns the pattern amounts in this currency must match.
+func (c Currency) amountRegexp() *regexp.Regexp {
+	return amount_regexps[c.Exponent]
+}
+
+// minorPerMajor is the number of minor units in one major unit, e.g. 100 cents to the dollar.
+func (c Currency) minorPerMajor() int64 {
+	m := int64(1)
+	for i := 0; i < c.Exponent; i++ {
+		m *= 10
+	}
+	return m
+}
+
+// minorUnits converts a validated amount to an integer count of minor units.
+func (c Currency) minorUnits(amount string) (minor int64, err error) {
+	if !c.amountRegexp().MatchString(amount) {
+		return 0, fmt.Errorf("amount %s is not a valid %s amount", amount, c.Code)
+	}
+	return strconv.ParseInt(strings.Replace(amount, ".", "", 1), 10, 64)
+}
+
+// currency returns the receipt's Currency; receipts stored without one are in USD.
+func (r *Receipt) currency() Currency {
+	if c, ok := LookupCurrency(r.Currency); ok {
+		return c
+	}
+	return Currencies[DefaultCurrency]
+}
+
+// exchangeRate returns the receipt's rate into the base currency; receipts stored without one are not converted.
+func (r *Receipt) exchangeRate() float64 {
+	if r.ExchangeRate <= 0 {
+		return 1
+	}
+	return r.ExchangeRate
+}
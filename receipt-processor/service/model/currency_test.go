@@ -0,0 +1,183 @@
+package model_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+func Test_ProcessReceipt_Currency(t *testing.T) {
+	type testCase struct {
+		currency     string
+		total        string
+		wantCurrency string
+		errExpected  bool
+	}
+
+	var testCases = []testCase{
+		{currency: "", total: "40.29", wantCurrency: "USD"},
+		{currency: "usd", total: "40.29", wantCurrency: "USD"},
+		{currency: "EUR", total: "40.29", wantCurrency: "EUR"},
+		{currency: "JPY", total: "4029", wantCurrency: "JPY"},
+		{currency: "KWD", total: "40.290", wantCurrency: "KWD"},
+		// amounts must have exactly as many decimals as the currency's minor unit
+		{currency: "JPY", total: "40.29", errExpected: true},
+		{currency: "KWD", total: "40.29", errExpected: true},
+		{currency: "USD", total: "4029", errExpected: true},
+		{currency: "XYZ", total: "40.29", errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		r := &pb.Receipt{
+			Retailer:     "TestTarget",
+			PurchaseDate: "2025-01-21",
+			PurchaseTime: "13:43",
+			Items: []*pb.Item{
+				{
+					ShortDescription: "An item at Target",
+					Price:            tc.total,
+				},
+			},
+			Total:    tc.total,
+			Currency: tc.currency,
+		}
+
+		rec, err := model.ProcessReceipt(context.Background(), r)
+		if err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error processing receipt in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		} else if err == nil && rec.Currency != tc.wantCurrency {
+			t.Errorf("Receipt currency was not normalized in test case %d: expected %s, received %s", i+1, tc.wantCurrency, rec.Currency)
+		}
+	}
+}
+
+func Test_AwardPoints_Currency(t *testing.T) {
+	type testCase struct {
+		currency string
+		total    string
+		baseline string // a total in the same currency earning no total points
+		bonus    int64
+	}
+
+	var testCases = []testCase{
+		{currency: "USD", total: "10.00", baseline: "10.01", bonus: 50},
+		{currency: "USD", total: "10.75", baseline: "10.01", bonus: 25},
+		{currency: "", total: "10.50", baseline: "10.01", bonus: 25},
+		{currency: "EUR", total: "10.00", baseline: "10.01", bonus: 50},
+		// there are no yen cents, so neither rule applies
+		{currency: "JPY", total: "1000", baseline: "1001", bonus: 0},
+		{currency: "JPY", total: "1025", baseline: "1001", bonus: 0},
+		{currency: "KWD", total: "10.000", baseline: "10.001", bonus: 50},
+		{currency: "KWD", total: "10.250", baseline: "10.001", bonus: 25},
+		{currency: "KWD", total: "10.025", baseline: "10.001", bonus: 0},
+	}
+
+	for i, tc := range testCases {
+		r := &model.Receipt{
+			Retailer: "TestTarget",
+			Date:     "2025-01-21",
+			Time:     "13:43",
+			Total:    tc.total,
+			Currency: tc.currency,
+			Items: []*model.Item{
+				{
+					ShortDescription: "An item at Target",
+					Price:            "10.00",
+				},
+			},
+		}
+		award := model.AwardPoints(r)
+		r.Total = tc.baseline
+		if baseline := model.AwardPoints(r); award-baseline != tc.bonus {
+			t.Errorf("Expected total points were not awarded in test case %d: expected %d, got %d", i+1, tc.bonus, award-baseline)
+		}
+	}
+}
+
+// fixedRates is a RateProvider with a single rate per currency, regardless of date.
+type fixedRates map[string]float64
+
+func (f fixedRates) Rate(currency string, on time.Time) (rate float64, err error) {
+	if currency == "USD" {
+		return 1, nil
+	} else if rate, ok := f[currency]; ok {
+		return rate, nil
+	}
+	return 0, errors.New("no rate")
+}
+
+func TestProcessor_ProcessReceipt_ExchangeRate(t *testing.T) {
+	type testCase struct {
+		currency    string
+		rates       model.RateProvider
+		rate        float64
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{currency: "USD", rates: fixedRates{}, rate: 1},
+		{currency: "CAD", rates: fixedRates{"CAD": 0.69}, rate: 0.69},
+		// without a rate provider, prices are not converted
+		{currency: "CAD", rates: nil, rate: 1},
+		// receipts that can't be converted are rejected
+		{currency: "GBP", rates: fixedRates{"CAD": 0.69}, errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		p := model.NewProcessor()
+		p.Rates = tc.rates
+
+		r := &pb.Receipt{
+			Retailer:     "TestTarget",
+			PurchaseDate: "2025-01-21",
+			PurchaseTime: "13:43",
+			Items: []*pb.Item{
+				{
+					ShortDescription: "An item at Target",
+					Price:            "40.29",
+				},
+			},
+			Total:    "40.29",
+			Currency: tc.currency,
+		}
+
+		rec, err := p.ProcessReceipt(context.Background(), r)
+		if err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error processing receipt in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		} else if err == nil && rec.ExchangeRate != tc.rate {
+			t.Errorf("Wrong exchange rate in test case %d: expected %v, received %v", i+1, tc.rate, rec.ExchangeRate)
+		}
+	}
+}
+
+func Test_AwardPoints_ExchangeRate(t *testing.T) {
+	r := &model.Receipt{
+		Retailer: "TestTarget",
+		Date:     "2025-01-21",
+		Time:     "13:43",
+		Total:    "100.01",
+		Currency: "GBP",
+		Items: []*model.Item{
+			{
+				// 17 characters, not a multiple of 3
+				ShortDescription: "An item at Target",
+				Price:            "100.01",
+			},
+		},
+	}
+	unconverted := model.AwardPoints(r)
+
+	// £100.01 is $125.01, worth 250 more points
+	r.ExchangeRate = 1.25
+	if converted := model.AwardPoints(r); converted-unconverted != 250 {
+		t.Errorf("Item prices were not converted to the base currency: expected 250 more points, got %d", converted-unconverted)
+	}
+}
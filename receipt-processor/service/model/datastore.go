@@ -1,45 +1,129 @@
 package model
 
 import (
+	"context"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ReceiptDB struct {
-	Store map[string]*Receipt
+	Store    map[string]*Receipt
+	LockWait func(op string, wait time.Duration) // Optional. Observes how long each operation waited for the store's lock.
+	closed   bool
 	sync.RWMutex
 }
 
-func (db *ReceiptDB) Create(r *Receipt) (id string, err error) {
+// errClosed is returned by operations on a closed store.
+var errClosed = ErrInternalServer("Receipt store is closed")
+
+func (db *ReceiptDB) Create(ctx context.Context, r *Receipt) (id string, err error) {
+	_, span := tracer.Start(ctx, "ReceiptDB.Create")
+	defer func() { endSpan(span, err) }()
 	// yes, generate an id & proceed
 	if id, err := idFactory(); err != nil {
 		return "", ErrInternalServer(err.Error())
 	} else {
-		db.Lock()
+		span.SetAttributes(attribute.String("receipt.id", id))
+		db.lock(span, "create")
 		defer db.Unlock()
+		if db.closed {
+			return "", errClosed
+		}
 		db.Store[id] = r
 		return id, nil
 	}
 }
 
-func (db *ReceiptDB) Set(idSet string, r *Receipt) (id string, err error) {
+func (db *ReceiptDB) Set(ctx context.Context, idSet string, r *Receipt) (id string, err error) {
+	_, span := tracer.Start(ctx, "ReceiptDB.Set", trace.WithAttributes(attribute.String("receipt.id", idSet)))
+	defer func() { endSpan(span, err) }()
 	if idSet == "" {
 		return "", ErrBadRequest("No Receipt ID was provided to Set")
 	} else {
 		id = idSet
-		db.Lock()
+		db.lock(span, "set")
 		defer db.Unlock()
+		if db.closed {
+			return "", errClosed
+		}
 		db.Store[id] = r
 		return id, nil
 	}
 
 }
 
-func (db *ReceiptDB) Get(id string) (receipt *Receipt, err error) {
-	db.RLock()
+func (db *ReceiptDB) Get(ctx context.Context, id string) (receipt *Receipt, err error) {
+	_, span := tracer.Start(ctx, "ReceiptDB.Get", trace.WithAttributes(attribute.String("receipt.id", id)))
+	defer func() { endSpan(span, err) }()
+	db.rlock(span, "get")
 	defer db.RUnlock()
-	if r, exists := db.Store[id]; !exists {
+	if db.closed {
+		return &Receipt{}, errClosed
+	} else if r, exists := db.Store[id]; !exists {
 		return &Receipt{}, ErrNotFound("Receipt was not found for receipt id: " + id)
 	} else {
 		return r, nil
 	}
 }
+
+// Delete removes a receipt from the store, such as one rejected on review.
+func (db *ReceiptDB) Delete(ctx context.Context, id string) (err error) {
+	_, span := tracer.Start(ctx, "ReceiptDB.Delete", trace.WithAttributes(attribute.String("receipt.id", id)))
+	defer func() { endSpan(span, err) }()
+	db.lock(span, "delete")
+	defer db.Unlock()
+	if db.closed {
+		return errClosed
+	} else if _, exists := db.Store[id]; !exists {
+		return ErrNotFound("Receipt was not found for receipt id: " + id)
+	}
+	delete(db.Store, id)
+	return nil
+}
+
+// Ready reports whether the store can serve receipts.
+func (db *ReceiptDB) Ready() (err error) {
+	db.RLock()
+	defer db.RUnlock()
+	if db.closed {
+		return errClosed
+	} else if db.Store == nil {
+		return ErrInternalServer("Receipt store is not initialized")
+	}
+	return nil
+}
+
+// Close closes the store once the operations in flight finish, failing any later ones.
+// Receipts are only held in memory, so there's nothing to flush; they're lost with the process.
+func (db *ReceiptDB) Close(ctx context.Context) (err error) {
+	_, span := tracer.Start(ctx, "ReceiptDB.Close")
+	defer func() { endSpan(span, err) }()
+	db.lock(span, "close")
+	defer db.Unlock()
+	db.closed = true
+	return nil
+}
+
+// lock takes the store's write lock for an operation, reporting how long it waited.
+func (db *ReceiptDB) lock(span trace.Span, op string) {
+	start := time.Now()
+	db.Lock()
+	db.waited(span, op, time.Since(start))
+}
+
+// rlock takes the store's read lock for an operation, reporting how long it waited.
+func (db *ReceiptDB) rlock(span trace.Span, op string) {
+	start := time.Now()
+	db.RLock()
+	db.waited(span, op, time.Since(start))
+}
+
+func (db *ReceiptDB) waited(span trace.Span, op string, wait time.Duration) {
+	span.SetAttributes(attribute.Int64("lock.wait_ns", wait.Nanoseconds()))
+	if db.LockWait != nil {
+		db.LockWait(op, wait)
+	}
+}
@@ -1,7 +1,10 @@
 package model_test
 
 import (
+	"context"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
 )
@@ -27,7 +30,7 @@ func TestReceiptDB_Create(t *testing.T) {
 		},
 	}
 
-	if id, err := testDB.Create(r); err != nil {
+	if id, err := testDB.Create(context.Background(), r); err != nil {
 		t.Errorf("Error encountered setting test receipt into DB: %d", err)
 	} else if receipt := testDB.Store[id]; receipt != r {
 		t.Errorf("Receipt set in DB is not identical to provided receipt: expected %v, received %v", r, receipt)
@@ -64,13 +67,13 @@ func TestReceiptDB_Set(t *testing.T) {
 		Awarded: true,
 	}
 
-	if id, err := testDB.Set(testId, set); err != nil {
+	if id, err := testDB.Set(context.Background(), testId, set); err != nil {
 		t.Errorf("Error encountered setting test receipt into DB: %d", err)
 	} else if receipt := testDB.Store[id]; receipt != set {
 		t.Errorf("Receipt set in DB is not identical to provided receipt: expected %v, received %v", set, receipt)
 	}
 
-	if _, err := testDB.Set("", set); err == nil {
+	if _, err := testDB.Set(context.Background(), "", set); err == nil {
 		t.Error("Expected BadRequest error was not encountered when not providing an id to Set")
 	}
 }
@@ -93,13 +96,84 @@ func TestReceiptDB_Get(t *testing.T) {
 
 	testDB.Store[testId] = r
 
-	if receipt, err := testDB.Get(testId); err != nil {
+	if receipt, err := testDB.Get(context.Background(), testId); err != nil {
 		t.Errorf("Error encountered getting test receipt from DB: %d", err)
 	} else if receipt != r {
 		t.Errorf("Receipt set in DB is not identical to provided receipt: expected %v, received %v", r, receipt)
 	}
 
-	if _, err := testDB.Get(noExist); err == nil {
+	if _, err := testDB.Get(context.Background(), noExist); err == nil {
 		t.Error("Expected BadRequest error not encountered fetching nonexistent receipt from DB")
 	}
 }
+
+func TestReceiptDB_Delete(t *testing.T) {
+	var testDB = model.ReceiptDB{Store: make(map[string]*model.Receipt)}
+	id, err := testDB.Create(context.Background(), &model.Receipt{Retailer: "TestTarget"})
+	if err != nil {
+		t.Fatalf("Unexpected error creating receipt: %v", err)
+	}
+	if err := testDB.Delete(context.Background(), id); err != nil {
+		t.Errorf("Unexpected error deleting receipt: %v", err)
+	}
+	if _, err := testDB.Get(context.Background(), id); err == nil {
+		t.Errorf("Did not receive expected error getting deleted receipt")
+	}
+	if err := testDB.Delete(context.Background(), id); err == nil {
+		t.Errorf("Did not receive expected error deleting missing receipt")
+	}
+}
+
+func TestReceiptDB_LockWait(t *testing.T) {
+	ops := make([]string, 0)
+	var testDB = model.ReceiptDB{Store: make(map[string]*model.Receipt), LockWait: func(op string, wait time.Duration) {
+		ops = append(ops, op)
+	}}
+
+	id, err := testDB.Create(context.Background(), &model.Receipt{Retailer: "TestTarget"})
+	if err != nil {
+		t.Fatalf("Unexpected error creating receipt: %v", err)
+	}
+	testDB.Set(context.Background(), id, &model.Receipt{Retailer: "TestTarget", Awarded: true})
+	testDB.Get(context.Background(), id)
+	if want := []string{"create", "set", "get"}; !slices.Equal(ops, want) {
+		t.Errorf("Wrong lock waits observed: expected %v, received %v", want, ops)
+	}
+}
+
+func TestReceiptDB_Ready(t *testing.T) {
+	if err := (&model.ReceiptDB{Store: make(map[string]*model.Receipt)}).Ready(); err != nil {
+		t.Errorf("Unexpected error checking initialized store: %v", err)
+	}
+	if err := (&model.ReceiptDB{}).Ready(); err == nil {
+		t.Errorf("Did not receive expected error checking uninitialized store")
+	}
+}
+
+func TestReceiptDB_Close(t *testing.T) {
+	var testDB = model.ReceiptDB{Store: make(map[string]*model.Receipt)}
+	id, err := testDB.Create(context.Background(), &model.Receipt{Retailer: "TestTarget"})
+	if err != nil {
+		t.Fatalf("Unexpected error creating receipt: %v", err)
+	}
+	if err := testDB.Close(context.Background()); err != nil {
+		t.Fatalf("Unexpected error closing store: %v", err)
+	}
+
+	// closed stores are no longer ready, & fail every operation
+	if err := testDB.Ready(); err == nil {
+		t.Errorf("Did not receive expected error checking closed store")
+	}
+	if _, err := testDB.Create(context.Background(), &model.Receipt{Retailer: "TestTarget"}); err == nil {
+		t.Errorf("Did not receive expected error creating receipt in closed store")
+	}
+	if _, err := testDB.Set(context.Background(), id, &model.Receipt{Retailer: "TestTarget", Awarded: true}); err == nil {
+		t.Errorf("Did not receive expected error setting receipt in closed store")
+	}
+	if _, err := testDB.Get(context.Background(), id); err == nil {
+		t.Errorf("Did not receive expected error getting receipt from closed store")
+	}
+	if err := testDB.Delete(context.Background(), id); err == nil {
+		t.Errorf("Did not receive expected error deleting receipt from closed store")
+	}
+}
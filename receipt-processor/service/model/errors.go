@@ -2,6 +2,8 @@ package model
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 )
 
 const (
@@ -21,3 +23,36 @@ func ErrBadRequest(cause string) error {
 func ErrInternalServer(cause string) error {
 	return fmt.Errorf("unexpected error %d - %s", Internal, cause)
 }
+
+// A ValidationError reports each invalid field & violated policy limit of a rejected receipt.
+type ValidationError struct {
+	Invalid  []string // Fields which are malformed.
+	Violated []string // Policy limits which were exceeded, one description per violation.
+}
+
+func (e *ValidationError) Error() string {
+	causes := make([]string, 0)
+	if len(e.Invalid) > 0 {
+		causes = append(causes, fmt.Sprintf("fields are invalid %s", e.Invalid))
+	}
+	if len(e.Violated) > 0 {
+		causes = append(causes, fmt.Sprintf("limits are violated [%s]", strings.Join(e.Violated, "; ")))
+	}
+	return ErrBadRequest("Receipt is invalid: " + strings.Join(causes, ", ")).Error()
+}
+
+var index_regexp = regexp.MustCompile(`\[\d+\]`)
+
+// Fields returns the field of each invalid field & violated limit, without item indexes, e.g. "items.shortDescription".
+func (e *ValidationError) Fields() (fields []string) {
+	fields = append(fields, e.Invalid...)
+	for _, v := range e.Violated {
+		// violations are described as "field: ..." or "field is required"
+		field, _, found := strings.Cut(v, ":")
+		if !found {
+			field, _, _ = strings.Cut(v, " ")
+		}
+		fields = append(fields, index_regexp.ReplaceAllString(field, ""))
+	}
+	return fields
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// An ItemClassifier identifies the brand & product category of an item from its normalized description.
+type ItemClassifier interface {
+	// Classify returns the brand & category of an item; either is empty when it can't be identified.
+	Classify(normalized string) (brand string, category string)
+}
+
+// NormalizeDescription reduces a short description to the form items are matched & classified by:
+// NFC normalized, trimmed, with runs of whitespace collapsed to a single space, and case folded,
+// so "  GATORADE  Cool Blue" & "Gatorade Cool Blue" normalize alike.
+func NormalizeDescription(description string) string {
+	// a Caser is stateful, so can't be shared between requests
+	return cases.Fold().String(strings.Join(strings.Fields(norm.NFC.String(description)), " "))
+}
+
+// normalizeItem fills in an item's normalized description, and its brand & category when a classifier is given.
+func normalizeItem(item *Item, classifier ItemClassifier) {
+	item.Normalized = NormalizeDescription(item.ShortDescription)
+	if classifier != nil {
+		item.Brand, item.Category = classifier.Classify(item.Normalized)
+	}
+}
+
+// trimmedLength returns the length of a short description, in characters, without leading or trailing whitespace.
+func (item *Item) trimmedLength() int {
+	return utf8.RuneCountInString(strings.TrimSpace(item.ShortDescription))
+}
@@ -0,0 +1,94 @@
+package model_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// brandClassifier is an ItemClassifier recognizing brands by the first word of a description.
+type brandClassifier map[string]string
+
+func (b brandClassifier) Classify(normalized string) (brand string, category string) {
+	first, _, _ := strings.Cut(normalized, " ")
+	if category, ok := b[first]; ok {
+		return first, category
+	}
+	return "", ""
+}
+
+func TestProcessor_ProcessReceipt_Items(t *testing.T) {
+	type testCase struct {
+		description    string
+		classifier     model.ItemClassifier
+		wantNormalized string
+		wantBrand      string
+		wantCategory   string
+	}
+
+	var known = brandClassifier{"gatorade": "beverages", "doritos": "snacks"}
+
+	var testCases = []testCase{
+		{description: "Gatorade", classifier: known, wantNormalized: "gatorade", wantBrand: "gatorade", wantCategory: "beverages"},
+		{description: "  GATORADE \t Cool   Blue ", classifier: known, wantNormalized: "gatorade cool blue", wantBrand: "gatorade", wantCategory: "beverages"},
+		{description: "Doritos Nacho", classifier: known, wantNormalized: "doritos nacho", wantBrand: "doritos", wantCategory: "snacks"},
+		// decomposed & case-variant descriptions normalize alike
+		{description: "Crème BRÛLÉE", classifier: known, wantNormalized: "crème brûlée"},
+		// items are still normalized without a classifier
+		{description: " Gatorade ", classifier: nil, wantNormalized: "gatorade"},
+	}
+
+	for i, tc := range testCases {
+		p := model.NewProcessor()
+		p.Items = tc.classifier
+
+		rec, err := p.ProcessReceipt(context.Background(), &pb.Receipt{
+			Retailer:     "Target",
+			PurchaseDate: "2025-01-21",
+			PurchaseTime: "13:43",
+			Items:        []*pb.Item{{ShortDescription: tc.description, Price: "4.50"}},
+			Total:        "4.50",
+		})
+		if err != nil {
+			t.Errorf("Unexpected error processing receipt in test case %d: %v", i+1, err)
+			continue
+		}
+		item := rec.Items[0]
+		if item.Normalized != tc.wantNormalized || item.Brand != tc.wantBrand || item.Category != tc.wantCategory {
+			t.Errorf("Wrong item classification in test case %d: expected %q, %q & %q, received %q, %q & %q",
+				i+1, tc.wantNormalized, tc.wantBrand, tc.wantCategory, item.Normalized, item.Brand, item.Category)
+		}
+	}
+}
+
+func Test_AwardPoints_TrimmedDescription(t *testing.T) {
+	type testCase struct {
+		description string
+		points      int64
+	}
+
+	var testCases = []testCase{
+		// 24 characters once trimmed, a multiple of 3
+		{description: "Klarbrunn 12-PK 12 FL OZ", points: 87},
+		{description: "   Klarbrunn 12-PK 12 FL OZ  ", points: 87},
+		// 25 characters once trimmed
+		{description: "Klarbrunn 12-PK 12 FL OZ.", points: 187},
+		{description: " Klarbrunn 12-PK 12 FL OZ. ", points: 187},
+	}
+
+	for i, tc := range testCases {
+		r := &model.Receipt{
+			Retailer: "Target",
+			Date:     "2025-01-22",
+			Time:     "13:43",
+			Total:    "12.00",
+			Items:    []*model.Item{{ShortDescription: tc.description, Price: "12.00"}},
+		}
+		if award := model.AwardPoints(r); award != tc.points {
+			t.Errorf("Expected points were not awarded in test case %d: expected %d, got %d", i+1, tc.points, award)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Canonical purchase date & time formats, as stored on a Receipt
+const (
+	CanonicalDate = time.DateOnly
+	CanonicalTime = "15:04"
+)
+
+// Purchase date layouts, grouped by convention
+var (
+	ISODateLayouts = []string{"2006-1-2", "2006/1/2"}
+	USDateLayouts  = []string{"1/2/2006", "1-2-2006", "Jan 2, 2006", "January 2, 2006"}
+	EUDateLayouts  = []string{"2/1/2006", "2.1.2006", "2-1-2006", "2 Jan 2006", "2 January 2006"}
+)
+
+// Purchase time layouts, grouped by convention
+// Meridiem markers are upper-cased before parsing, so "1:43 pm" matches "3:04 PM".
+var (
+	TwentyFourHourTimeLayouts = []string{"15:04", "15:04:05"}
+	TwelveHourTimeLayouts     = []string{"3:04 PM", "3:04PM", "3:04:05 PM", "3:04:05PM"}
+)
+
+// DefaultDateLayouts & DefaultTimeLayouts are recognized by NewProcessor.
+var (
+	DefaultDateLayouts = slices.Concat(ISODateLayouts, USDateLayouts, EUDateLayouts)
+	DefaultTimeLayouts = slices.Concat(TwentyFourHourTimeLayouts, TwelveHourTimeLayouts)
+)
+
+// normalizeDate converts a purchase date in any of the given layouts to CanonicalDate.
+// A date that several layouts read as different days, such as 01/02/2025, is rejected as ambiguous.
+// A date no layout reads is returned unchanged, to be rejected by validation.
+func normalizeDate(date string, layouts []string) (normalized string, err error) {
+	return normalize("purchaseDate", strings.TrimSpace(date), layouts, CanonicalDate)
+}
+
+// normalizeTime converts a purchase time in any of the given layouts to CanonicalTime.
+// Seconds are discarded.
+func normalizeTime(t string, layouts []string) (normalized string, err error) {
+	return normalize("purchaseTime", strings.ToUpper(strings.TrimSpace(t)), layouts, CanonicalTime)
+}
+
+func normalize(field string, value string, layouts []string, canonical string) (normalized string, err error) {
+	matched := make([]string, 0)
+	for _, layout := range layouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			if f := parsed.Format(canonical); !slices.Contains(matched, f) {
+				matched = append(matched, f)
+			}
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return value, nil
+	case 1:
+		return matched[0], nil
+	default:
+		return "", ErrBadRequest(fmt.Sprintf("Receipt %s %q is ambiguous: could be any of %s", field, value, matched))
+	}
+}
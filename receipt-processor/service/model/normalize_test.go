@@ -0,0 +1,82 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+func TestProcessor_ProcessReceipt_Normalization(t *testing.T) {
+	type testCase struct {
+		date        string
+		time        string
+		layouts     *model.Processor
+		wantDate    string
+		wantTime    string
+		errExpected bool
+	}
+
+	var isoOnly = &model.Processor{DateLayouts: model.ISODateLayouts, TimeLayouts: model.TwentyFourHourTimeLayouts}
+	var usOnly = &model.Processor{DateLayouts: model.USDateLayouts, TimeLayouts: model.TwelveHourTimeLayouts}
+
+	var testCases = []testCase{
+		// canonical values pass through untouched
+		{date: "2025-01-21", time: "13:43", wantDate: "2025-01-21", wantTime: "13:43"},
+		// unpadded ISO, seconds & 12-hour times
+		{date: "2025-1-5", time: "13:43:59", wantDate: "2025-01-05", wantTime: "13:43"},
+		{date: "2025/01/21", time: "1:43 PM", wantDate: "2025-01-21", wantTime: "13:43"},
+		{date: "2025-01-21", time: "12:05am", wantDate: "2025-01-21", wantTime: "00:05"},
+		{date: "2025-01-21", time: " 9:15:00 am ", wantDate: "2025-01-21", wantTime: "09:15"},
+		// a day past 12 can only be read one way
+		{date: "01/21/2025", time: "13:43", wantDate: "2025-01-21", wantTime: "13:43"},
+		{date: "21/01/2025", time: "13:43", wantDate: "2025-01-21", wantTime: "13:43"},
+		{date: "21.01.2025", time: "13:43", wantDate: "2025-01-21", wantTime: "13:43"},
+		{date: "January 21, 2025", time: "13:43", wantDate: "2025-01-21", wantTime: "13:43"},
+		{date: "21 Jan 2025", time: "13:43", wantDate: "2025-01-21", wantTime: "13:43"},
+		// identical under US & EU readings
+		{date: "05/05/2025", time: "13:43", wantDate: "2025-05-05", wantTime: "13:43"},
+		// January 2nd or February 1st?
+		{date: "01/02/2025", time: "13:43", errExpected: true},
+		{date: "3-4-2025", time: "13:43", errExpected: true},
+		// unambiguous once only one convention is configured
+		{date: "01/02/2025", time: "1:43 PM", layouts: usOnly, wantDate: "2025-01-02", wantTime: "13:43"},
+		// layouts that are not configured are not recognized
+		{date: "01/21/2025", time: "13:43", layouts: isoOnly, errExpected: true},
+		{date: "2025-01-21", time: "1:43 PM", layouts: isoOnly, errExpected: true},
+		// unparseable values are still rejected by validation
+		{date: "2025-13-45", time: "13:43", errExpected: true},
+		{date: "2025-01-21", time: "26:99", errExpected: true},
+		{date: "2025-01-21", time: "13:43 PM", errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		p := tc.layouts
+		if p == nil {
+			p = model.NewProcessor()
+		}
+
+		r := &pb.Receipt{
+			Retailer:     "TestTarget",
+			PurchaseDate: tc.date,
+			PurchaseTime: tc.time,
+			Items: []*pb.Item{
+				{
+					ShortDescription: "An item at Target",
+					Price:            "40.29",
+				},
+			},
+			Total: "40.29",
+		}
+
+		rec, err := p.ProcessReceipt(context.Background(), r)
+		if err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error processing receipt in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		} else if err == nil && (rec.Date != tc.wantDate || rec.Time != tc.wantTime) {
+			t.Errorf("Receipt was not normalized in test case %d: expected %s %s, received %s %s", i+1, tc.wantDate, tc.wantTime, rec.Date, rec.Time)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// A ValidationPolicy bounds the receipts accepted for processing.
+// Zero limits are not enforced.
+type ValidationPolicy struct {
+	MaxItems             int           // Most items on a single receipt.
+	MaxDescriptionLength int           // Most characters in an item's short description.
+	MaxTotal             float64       // Largest total, in the base currency.
+	MaxAge               time.Duration // Furthest a purchase may be in the past.
+	MaxFuture            time.Duration // Furthest a purchase may be in the future, allowing for clock skew.
+	RequiredFields       []string      // Fields which may not be empty, by their JSON name.
+}
+
+// DefaultValidationPolicy is enforced by processors which aren't configured otherwise.
+var DefaultValidationPolicy = ValidationPolicy{
+	MaxItems:             250,
+	MaxDescriptionLength: 100,
+	MaxTotal:             1000000,
+	MaxFuture:            24 * time.Hour,
+	RequiredFields:       []string{"retailer", "purchaseDate", "purchaseTime", "items", "total"},
+}
+
+// Fields are the receipt fields a policy may require, by their JSON name.
+var Fields = []string{"retailer", "purchaseDate", "purchaseTime", "items", "total", "timeZone", "currency", "userId"}
+
+// Validate reports configuration errors in the policy itself.
+func (p *ValidationPolicy) Validate() (err error) {
+	if p.MaxItems < 0 || p.MaxDescriptionLength < 0 || p.MaxTotal < 0 || p.MaxAge < 0 || p.MaxFuture < 0 {
+		return fmt.Errorf("validation limits may not be negative")
+	}
+	for _, f := range p.RequiredFields {
+		if !slices.Contains(Fields, f) {
+			return fmt.Errorf("required field %s is not a receipt field", f)
+		}
+	}
+	return nil
+}
+
+// violations lists each limit the receipt exceeds. Limits on fields which
+// are themselves invalid are skipped, as they can't be meaningfully compared.
+func (p *ValidationPolicy) violations(r *Receipt, invalid []string, now time.Time) (violated []string) {
+	if p == nil {
+		return nil
+	}
+	violated = make([]string, 0)
+
+	for _, f := range p.RequiredFields {
+		if isEmpty(r, f) {
+			violated = append(violated, fmt.Sprintf("%s is required", f))
+		}
+	}
+
+	if p.MaxItems > 0 && len(r.Items) > p.MaxItems {
+		violated = append(violated, fmt.Sprintf("items: %d items exceeds the maximum of %d", len(r.Items), p.MaxItems))
+	}
+
+	if p.MaxDescriptionLength > 0 {
+		for i, item := range r.Items {
+			if n := utf8.RuneCountInString(item.ShortDescription); n > p.MaxDescriptionLength {
+				violated = append(violated, fmt.Sprintf("items[%d].shortDescription: %d characters exceeds the maximum of %d", i, n, p.MaxDescriptionLength))
+			}
+		}
+	}
+
+	// totals are compared in the base currency, so are skipped when the rate is unknown
+	if p.MaxTotal > 0 && r.ExchangeRate > 0 && !slices.Contains(invalid, "currency") && !slices.Contains(invalid, "total") {
+		c := r.currency()
+		if minor, err := c.minorUnits(r.Total); err == nil {
+			if total := float64(minor) / float64(c.minorPerMajor()) * r.ExchangeRate; total > p.MaxTotal {
+				violated = append(violated, fmt.Sprintf("total: %s %s exceeds the maximum of %.2f", r.Total, c.Code, p.MaxTotal))
+			}
+		}
+	}
+
+	if !r.PurchasedAt.IsZero() {
+		if p.MaxAge > 0 && r.PurchasedAt.Before(now.Add(-p.MaxAge)) {
+			violated = append(violated, fmt.Sprintf("purchaseDate: %s is older than the maximum of %s", r.Date, p.MaxAge))
+		}
+		if p.MaxFuture > 0 && r.PurchasedAt.After(now.Add(p.MaxFuture)) {
+			violated = append(violated, fmt.Sprintf("purchaseDate: %s %s is in the future", r.Date, r.Time))
+		}
+	}
+	return violated
+}
+
+// isEmpty reports whether the named field was left empty on the receipt.
+func isEmpty(r *Receipt, field string) bool {
+	switch field {
+	case "retailer":
+		return strings.TrimSpace(r.Retailer) == ""
+	case "purchaseDate":
+		return r.Date == ""
+	case "purchaseTime":
+		return r.Time == ""
+	case "items":
+		return len(r.Items) == 0
+	case "total":
+		return r.Total == ""
+	case "timeZone":
+		return r.TimeZone == ""
+	case "currency":
+		return r.Currency == ""
+	case "userId":
+		return r.UserID == ""
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package model_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+func TestProcessor_ProcessReceipt_Policy(t *testing.T) {
+	type testCase struct {
+		receipt      *pb.Receipt
+		policy       *model.ValidationPolicy
+		rates        model.RateProvider
+		wantViolated []string // a substring of each violation expected, in order
+		wantInvalid  []string
+	}
+
+	var now = time.Date(2025, 1, 21, 12, 0, 0, 0, time.UTC)
+	var item = &pb.Item{ShortDescription: "An item at Target", Price: "40.29"}
+	var receipt = func(edit func(r *pb.Receipt)) *pb.Receipt {
+		r := &pb.Receipt{
+			Retailer:     "TestTarget",
+			PurchaseDate: "2025-01-21",
+			PurchaseTime: "11:43",
+			Items:        []*pb.Item{item},
+			Total:        "40.29",
+		}
+		if edit != nil {
+			edit(r)
+		}
+		return r
+	}
+	var limits = &model.ValidationPolicy{
+		MaxItems:             2,
+		MaxDescriptionLength: 20,
+		MaxTotal:             100,
+		MaxAge:               30 * 24 * time.Hour,
+		MaxFuture:            time.Hour,
+		RequiredFields:       []string{"retailer", "items", "timeZone"},
+	}
+
+	var testCases = []testCase{
+		// within every limit
+		{receipt: receipt(func(r *pb.Receipt) { r.TimeZone = "UTC" }), policy: limits},
+		// each limit is reported on its own
+		{
+			receipt:      receipt(func(r *pb.Receipt) { r.TimeZone = "UTC"; r.Items = []*pb.Item{item, item, item} }),
+			policy:       limits,
+			wantViolated: []string{"items: 3 items exceeds the maximum of 2"},
+		},
+		{
+			receipt: receipt(func(r *pb.Receipt) {
+				r.TimeZone = "UTC"
+				r.Items = []*pb.Item{item, {ShortDescription: "A very long description of an item", Price: "40.29"}}
+			}),
+			policy:       limits,
+			wantViolated: []string{"items[1].shortDescription: 34 characters"},
+		},
+		{
+			receipt: receipt(func(r *pb.Receipt) {
+				r.TimeZone = "UTC"
+				r.Items[0] = &pb.Item{ShortDescription: "Television", Price: "400.29"}
+				r.Total = "400.29"
+			}),
+			policy:       limits,
+			wantViolated: []string{"total: 400.29 USD exceeds the maximum of 100.00"},
+		},
+		{
+			receipt:      receipt(func(r *pb.Receipt) { r.TimeZone = "UTC"; r.PurchaseDate = "2024-11-21" }),
+			policy:       limits,
+			wantViolated: []string{"purchaseDate: 2024-11-21 is older"},
+		},
+		{
+			receipt:      receipt(func(r *pb.Receipt) { r.TimeZone = "UTC"; r.PurchaseTime = "13:43" }),
+			policy:       limits,
+			wantViolated: []string{"purchaseDate: 2025-01-21 13:43 is in the future"},
+		},
+		{
+			receipt:      receipt(nil),
+			policy:       limits,
+			wantViolated: []string{"timeZone is required"},
+		},
+		// purchases are compared in the store's time zone: 11:43 in Tokyo was hours ago
+		{receipt: receipt(func(r *pb.Receipt) { r.TimeZone = "Asia/Tokyo"; r.PurchaseTime = "20:43" }), policy: limits},
+		// totals are compared in the base currency
+		{
+			receipt: receipt(func(r *pb.Receipt) {
+				r.TimeZone = "UTC"
+				r.Currency = "JPY"
+				r.Items[0] = &pb.Item{ShortDescription: "Television", Price: "14000"}
+				r.Total = "14000"
+			}),
+			policy: limits,
+			rates:  fixedRates{"JPY": 0.0064},
+		},
+		// several violations are all reported, alongside invalid fields
+		{
+			receipt: receipt(func(r *pb.Receipt) {
+				r.Retailer = ""
+				r.Items = []*pb.Item{item, item, {ShortDescription: "A very long description of an item", Price: "40.29"}}
+			}),
+			policy:       limits,
+			wantInvalid:  []string{"retailer"},
+			wantViolated: []string{"retailer is required", "timeZone is required", "items: 3 items", "items[2].shortDescription"},
+		},
+		// the submitting user may be required, and must be a valid id when given
+		{
+			receipt:      receipt(func(r *pb.Receipt) { r.TimeZone = "UTC" }),
+			policy:       &model.ValidationPolicy{RequiredFields: []string{"userId"}},
+			wantViolated: []string{"userId is required"},
+		},
+		{
+			receipt:     receipt(func(r *pb.Receipt) { r.UserId = "user 42" }),
+			wantInvalid: []string{"userId"},
+		},
+		{receipt: receipt(func(r *pb.Receipt) { r.UserId = "user-42" }), policy: &model.ValidationPolicy{RequiredFields: []string{"userId"}}},
+		// without a policy, no limits are enforced
+		{receipt: receipt(func(r *pb.Receipt) { r.PurchaseDate = "2035-01-21" })},
+	}
+
+	for i, tc := range testCases {
+		p := model.NewProcessor()
+		p.Policy = tc.policy
+		p.Rates = tc.rates
+		p.Now = func() time.Time { return now }
+
+		_, err := p.ProcessReceipt(context.Background(), tc.receipt)
+		if err != nil && tc.wantViolated == nil && tc.wantInvalid == nil {
+			t.Errorf("Unexpected error processing receipt in test case %d: %v", i+1, err)
+			continue
+		} else if err == nil && (tc.wantViolated != nil || tc.wantInvalid != nil) {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+			continue
+		} else if err == nil {
+			continue
+		}
+
+		var verr *model.ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("Expected a validation error in test case %d, received %v", i+1, err)
+			continue
+		}
+		if len(verr.Violated) != len(tc.wantViolated) || len(verr.Invalid) != len(tc.wantInvalid) {
+			t.Errorf("Wrong violations reported in test case %d: expected %v & %v, received %v & %v", i+1, tc.wantInvalid, tc.wantViolated, verr.Invalid, verr.Violated)
+			continue
+		}
+		for j, want := range tc.wantViolated {
+			if !strings.Contains(verr.Violated[j], want) {
+				t.Errorf("Wrong violation reported in test case %d: expected %q, received %q", i+1, want, verr.Violated[j])
+			}
+		}
+		for j, want := range tc.wantInvalid {
+			if verr.Invalid[j] != want {
+				t.Errorf("Wrong invalid field reported in test case %d: expected %q, received %q", i+1, want, verr.Invalid[j])
+			}
+		}
+	}
+}
+
+func TestValidationPolicy_Validate(t *testing.T) {
+	type testCase struct {
+		policy      model.ValidationPolicy
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{policy: model.DefaultValidationPolicy},
+		{policy: model.ValidationPolicy{}},
+		{policy: model.ValidationPolicy{RequiredFields: []string{"currency", "timeZone"}}},
+		{policy: model.ValidationPolicy{MaxItems: -1}, errExpected: true},
+		{policy: model.ValidationPolicy{RequiredFields: []string{"loyaltyNumber"}}, errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		if err := tc.policy.Validate(); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error validating policy in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+}
+
+func TestValidationError_Fields(t *testing.T) {
+	err := &model.ValidationError{
+		Invalid:  []string{"retailer", "date"},
+		Violated: []string{"total is required", "items[3].shortDescription: 120 characters exceeds the maximum of 100", "purchaseDate: 2099-01-01 13:01 is in the future"},
+	}
+	want := []string{"retailer", "date", "total", "items.shortDescription", "purchaseDate"}
+	if fields := err.Fields(); !slices.Equal(fields, want) {
+		t.Errorf("Wrong fields: expected %v, received %v", want, fields)
+	}
+}
@@ -1,77 +1,229 @@
 package model
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"math"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/text/unicode/norm"
 
 	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
 )
 
+// tracer traces receipt processing & store operations, once a tracer provider is installed.
+var tracer = otel.Tracer("github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model")
+
 type Receipt struct {
-	Retailer string
-	Date     string
-	Time     string
-	Total    string
-	Items    []*Item
-	Awarded  bool
+	Retailer     string
+	Date         string
+	Time         string
+	Total        string
+	Items        []*Item
+	Awarded      bool
+	TimeZone     string    // The store's time zone as provided, if any.
+	PurchasedAt  time.Time // The purchase date & time, in the store's time zone.
+	Currency     string    // The ISO 4217 code of the currency paid in.
+	ExchangeRate float64   // The value of one unit of Currency in the base currency on the purchase date.
+	UserID       string    // The user who submitted the receipt, if any.
+	RetailerID   string    // The canonical id of the known retailer Retailer refers to, if any.
+	Held         bool      // Whether points are withheld until the receipt is reviewed as likely fraudulent.
 }
 
 type Item struct {
-	ShortDescription string
+	ShortDescription string // The description as printed on the receipt.
 	Price            string
+	Normalized       string // The description trimmed, with whitespace collapsed & case folded.
+	Brand            string // The brand named in the description, if known.
+	Category         string // The product category, if the description could be classified.
 }
 
 type Points int64
 
-func ProcessReceipt(receipt *pb.Receipt) (validated Receipt, err error) {
+// A RetailerResolver identifies the known retailer a receipt's printed retailer name refers to.
+type RetailerResolver interface {
+	// Resolve returns the canonical id of the retailer a name refers to, if it's known.
+	Resolve(name string) (id string, ok bool)
+}
+
+// A Processor normalizes & validates receipts before they are stored.
+type Processor struct {
+	DateLayouts []string          // Purchase date layouts recognized & normalized to CanonicalDate.
+	TimeLayouts []string          // Purchase time layouts recognized & normalized to CanonicalTime.
+	Location    *time.Location    // Time zone assumed for receipts which don't specify their store's.
+	Rates       RateProvider      // Exchange rates into the base currency; item prices are not converted when nil.
+	Policy      *ValidationPolicy // Limits receipts must satisfy; none are enforced when nil.
+	Retailers   RetailerResolver  // Known retailers, which receipts are matched to by name; none are matched when nil.
+	Items       ItemClassifier    // Identifies each item's brand & category; items aren't classified when nil.
+	Now         func() time.Time  // Clock purchase dates are checked against; defaults to time.Now.
+	Logger      *slog.Logger      // Where rejected receipts are logged; defaults to slog's default logger.
+}
+
+// NewProcessor returns a Processor recognizing the default date & time layouts,
+// enforcing a copy of the DefaultValidationPolicy.
+func NewProcessor() *Processor {
+	policy := DefaultValidationPolicy
+	return &Processor{
+		DateLayouts: DefaultDateLayouts,
+		TimeLayouts: DefaultTimeLayouts,
+		Location:    time.UTC,
+		Policy:      &policy,
+	}
+}
+
+// ProcessReceipt processes a receipt with the default Processor.
+func ProcessReceipt(ctx context.Context, receipt *pb.Receipt) (validated Receipt, err error) {
+	return NewProcessor().ProcessReceipt(ctx, receipt)
+}
+
+func (p *Processor) ProcessReceipt(ctx context.Context, receipt *pb.Receipt) (validated Receipt, err error) {
+	ctx, span := tracer.Start(ctx, "model.ProcessReceipt")
+	defer func() { endSpan(span, err) }()
+
 	// parse receipt items
 	receiptItems := make([]*Item, 0)
 	for _, item := range receipt.GetItems() {
-		parsed := Item{item.GetShortDescription(), item.GetPrice()}
+		parsed := Item{ShortDescription: norm.NFC.String(item.GetShortDescription()), Price: item.GetPrice()}
+		normalizeItem(&parsed, p.Items)
 		receiptItems = append(receiptItems, &parsed)
 	}
 
-	rec := Receipt{receipt.GetRetailer(), receipt.GetPurchaseDate(), receipt.GetPurchaseTime(), receipt.GetTotal(), receiptItems, false}
+	// normalize purchase date & time to their canonical formats
+	date, err := normalizeDate(receipt.GetPurchaseDate(), p.DateLayouts)
+	if err != nil {
+		p.logger().WarnContext(ctx, "Receipt could not be normalized", "error", err)
+		return Receipt{}, err
+	}
+	purchaseTime, err := normalizeTime(receipt.GetPurchaseTime(), p.TimeLayouts)
+	if err != nil {
+		p.logger().WarnContext(ctx, "Receipt could not be normalized", "error", err)
+		return Receipt{}, err
+	}
+
+	rec := Receipt{
+		Retailer: norm.NFC.String(receipt.GetRetailer()),
+		Date:     date,
+		Time:     purchaseTime,
+		Total:    receipt.GetTotal(),
+		Items:    receiptItems,
+		TimeZone: receipt.GetTimeZone(),
+		Currency: strings.ToUpper(strings.TrimSpace(receipt.GetCurrency())),
+		UserID:   receipt.GetUserId(),
+	}
+
+	// the purchase instant & exchange rate are resolved ahead of validation, as
+	// policy limits are checked against them; they're unknown for invalid fields
+	loc, zoneErr := loadLocation(rec.TimeZone, p.location())
+	if zoneErr == nil {
+		rec.PurchasedAt, _ = purchasedAt(rec.Date, rec.Time, loc)
+	}
+	var rateErr error
+	rec.ExchangeRate = 1
+	if p.Rates != nil && !rec.PurchasedAt.IsZero() {
+		if rec.ExchangeRate, rateErr = p.Rates.Rate(rec.currency().Code, rec.PurchasedAt); rateErr != nil {
+			rec.ExchangeRate = 0
+		}
+	}
 
 	// validate our fields
-	if err := validateReceipt(receipt.GetRetailer(), receipt.GetPurchaseDate(), receipt.GetPurchaseTime(), receipt.GetTotal(), receiptItems); err != nil {
-		log.Printf("Error encountered validating receipt: %s", err.Error())
+	_, validation := tracer.Start(ctx, "model.validateReceipt", trace.WithAttributes(attribute.Int("receipt.items", len(rec.Items))))
+	err = validateReceipt(&rec, zoneErr, p.Policy, p.now())
+	endSpan(validation, err)
+	if err != nil {
+		p.logger().WarnContext(ctx, "Receipt failed validation", "error", err)
 		return Receipt{}, err
-	} else {
-		validated = rec
-		return validated, nil
 	}
+	if rec.Currency == "" {
+		rec.Currency = DefaultCurrency
+	}
+
+	// the printed retailer name is kept as-is, alongside the known retailer it refers to
+	if p.Retailers != nil {
+		rec.RetailerID, _ = p.Retailers.Resolve(rec.Retailer)
+	}
+
+	// item points are computed in the base currency, at the rate on the purchase date
+	if rateErr != nil {
+		p.logger().WarnContext(ctx, "Receipt currency could not be converted", "currency", rec.Currency, "date", rec.Date, "error", rateErr)
+		return Receipt{}, ErrBadRequest(fmt.Sprintf("Receipt currency %s cannot be converted on %s", rec.Currency, rec.Date))
+	}
+	validated = rec
+	return validated, nil
+}
+
+// endSpan ends a span, marking it failed when the operation it traced returned an error.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// location returns the time zone assumed for receipts which don't specify one.
+func (p *Processor) location() *time.Location {
+	if p.Location == nil {
+		return time.UTC
+	}
+	return p.Location
+}
+
+// now returns the current time, as seen by the processor's clock.
+func (p *Processor) now() time.Time {
+	if p.Now == nil {
+		return time.Now()
+	}
+	return p.Now()
+}
+
+// logger returns the logger rejected receipts are logged to.
+func (p *Processor) logger() *slog.Logger {
+	if p.Logger == nil {
+		return slog.Default()
+	}
+	return p.Logger
 }
 
 func AwardPoints(r *Receipt) (awardPoints int64) {
 	var pendingPts int64
 
 	// Points for the Retailer field
-	pendingPts = pendingPts + int64(len(alphanumeric_regexp.FindAllString(r.Retailer, -1)))
+	// One point for every alphanumeric character in the retailer name.
+	pendingPts = pendingPts + countAlphanumeric(r.Retailer)
+
+	// Date & time rules are evaluated in the store's local time
+	local := r.localPurchaseTime()
 
 	// Points for the Purchase Date field
-	split := strings.Split(r.Date, "-")
-	if parsed, _ := strconv.ParseInt(split[2], 10, 64); parsed%2 == 0 {
+	if local.Day()%2 == 0 {
 		// 6 points if the day in the purchase date is odd.
 		pendingPts = pendingPts + 6
 	}
 
 	// Points for the Purchase Time field
-	if t, _ := time.Parse(time.TimeOnly, r.Time+":00"); t.Hour() < 16 && t.Hour() > 14 {
+	if local.Hour() < 16 && local.Hour() > 14 {
 		// 10 points if the time of purchase is after 2:00pm and before 4:00pm.
 		pendingPts = pendingPts + 10
 	}
 
 	// Points for the Total field
-	if split := strings.Split(r.Total, "."); split[1] == "00" {
-		// 50 points if the total is a round dollar amount with no cents.
-		pendingPts = pendingPts + 50
-	} else if split[1] == "25" || split[1] == "50" || split[1] == "75" {
-		// 25 points if the total is a multiple of 0.25.
-		pendingPts = pendingPts + 25
+	// Round & quarter amounts only mean something for currencies with a minor unit
+	currency := r.currency()
+	if total, err := currency.minorUnits(r.Total); err == nil && currency.Exponent > 0 {
+		if total%currency.minorPerMajor() == 0 {
+			// 50 points if the total is a round dollar amount with no cents.
+			pendingPts = pendingPts + 50
+		} else if total%(currency.minorPerMajor()/4) == 0 {
+			// 25 points if the total is a multiple of 0.25.
+			pendingPts = pendingPts + 25
+		}
 	}
 
 	// Points for the Items field
@@ -83,20 +235,20 @@ func AwardPoints(r *Receipt) (awardPoints int64) {
 
 		// If the trimmed length of the item description is a multiple of 3, multiply the price by 0.2 and round up to the nearest integer.
 		// The result is the number of points earned.
-		if len(item.ShortDescription)%3 == 0 {
+		if item.trimmedLength()%3 == 0 {
 			// our data is sanitized, item prices conform to regex
-			// since prices are decimals, parse as float64
+			// since prices are decimals, parse as float64, then convert to the base currency
 			unadjusted, _ := strconv.ParseFloat(item.Price, 64)
-			adjusted := int64(math.Round(unadjusted * 0.2))
+			adjusted := int64(math.Round(unadjusted * r.exchangeRate() * 0.2))
 			// ensure we account for any previously calculated dollars spent
 			pendingPts = pendingPts + (adjusted * 10)
 		} else {
 			// our data is sanitized, item prices conform to regex
-			// since prices are decimals, parse as float64
+			// since prices are decimals, parse as float64, then convert to the base currency
 			unadjusted, _ := strconv.ParseFloat(item.Price, 64)
 			// round to the nearest whole number,
 			// and convert to int64 to conform to API spec
-			award := int64(math.Round(unadjusted))
+			award := int64(math.Round(unadjusted * r.exchangeRate()))
 			// ensure we account for any previously calculated dollars spent
 			pendingPts = pendingPts + (award * 10)
 		}
@@ -107,3 +259,24 @@ func AwardPoints(r *Receipt) (awardPoints int64) {
 	return
 
 }
+
+// localPurchaseTime returns the purchase date & time in the store's time zone.
+// Receipts stored without a PurchasedAt fall back to their printed date & time.
+func (r *Receipt) localPurchaseTime() time.Time {
+	if !r.PurchasedAt.IsZero() {
+		return r.PurchasedAt
+	}
+	t, _ := purchasedAt(r.Date, r.Time, time.UTC)
+	return t
+}
+
+// countAlphanumeric counts the letters & digits in a string, in any script.
+// Strings are NFC normalized when processed, so "é" is one letter whether it arrived composed or not.
+func countAlphanumeric(s string) (count int64) {
+	for _, r := range norm.NFC.String(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			count++
+		}
+	}
+	return count
+}
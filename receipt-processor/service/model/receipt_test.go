@@ -1,6 +1,7 @@
 package model_test
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -137,7 +138,7 @@ func Test_ProcessReceipt(t *testing.T) {
 		},
 	}
 	for i, tc := range testCases {
-		if _, err := model.ProcessReceipt(tc.receipt); err != nil && !tc.errExpected {
+		if _, err := model.ProcessReceipt(context.Background(), tc.receipt); err != nil && !tc.errExpected {
 			t.Errorf("Unexpected error processing receipt in test case %d: %v", i+1, err)
 		} else if err == nil && tc.errExpected {
 			t.Errorf("Did not receive expected error in test case %d", i+1)
@@ -159,7 +160,7 @@ func Test_ProcessReceipt_JSON(t *testing.T) {
 		// outside of test helper methods
 		if r, err := unmarshalHelper(tc); err != nil {
 			t.Errorf("Error unmarshaling JSON in test case %d: %v", i+1, err)
-		} else if _, err := model.ProcessReceipt(r); err != nil {
+		} else if _, err := model.ProcessReceipt(context.Background(), r); err != nil {
 			t.Errorf("Error processing receipt in test case %d: %v", i+1, err)
 		}
 	}
@@ -185,7 +186,7 @@ func Test_AwardPoints(t *testing.T) {
 					},
 				},
 			},
-			points: 406,
+			points: 415,
 		},
 		{
 			receipt: &model.Receipt{
@@ -200,7 +201,7 @@ func Test_AwardPoints(t *testing.T) {
 					},
 				},
 			},
-			points: 2000000056,
+			points: 2000000069,
 		},
 		{
 			receipt: &model.Receipt{
@@ -230,6 +231,51 @@ func Test_AwardPoints(t *testing.T) {
 	}
 }
 
+// knownRetailers is a RetailerResolver matching exact names only.
+type knownRetailers map[string]string
+
+func (k knownRetailers) Resolve(name string) (id string, ok bool) {
+	id, ok = k[name]
+	return id, ok
+}
+
+func TestProcessor_ProcessReceipt_Retailer(t *testing.T) {
+	type testCase struct {
+		retailer   string
+		resolver   model.RetailerResolver
+		retailerID string
+	}
+
+	var known = knownRetailers{"M & M CORNER MKT": "m-and-m-corner-market"}
+
+	var testCases = []testCase{
+		{retailer: "M & M CORNER MKT", resolver: known, retailerID: "m-and-m-corner-market"},
+		// unknown retailers are still accepted, without a canonical id
+		{retailer: "Corner Shop", resolver: known, retailerID: ""},
+		{retailer: "M & M CORNER MKT", resolver: nil, retailerID: ""},
+	}
+
+	for i, tc := range testCases {
+		p := model.NewProcessor()
+		p.Retailers = tc.resolver
+
+		rec, err := p.ProcessReceipt(context.Background(), &pb.Receipt{
+			Retailer:     tc.retailer,
+			PurchaseDate: "2025-01-21",
+			PurchaseTime: "13:43",
+			Items:        []*pb.Item{{ShortDescription: "An item", Price: "40.29"}},
+			Total:        "40.29",
+		})
+		if err != nil {
+			t.Errorf("Unexpected error processing receipt in test case %d: %v", i+1, err)
+		} else if rec.RetailerID != tc.retailerID {
+			t.Errorf("Wrong retailer id in test case %d: expected %q, received %q", i+1, tc.retailerID, rec.RetailerID)
+		} else if rec.Retailer != tc.retailer {
+			t.Errorf("Printed retailer name was not preserved in test case %d: expected %q, received %q", i+1, tc.retailer, rec.Retailer)
+		}
+	}
+}
+
 func unmarshalHelper(body string) (r *pb.Receipt, err error) {
 	if err = json.Unmarshal([]byte(body), &r); err != nil {
 		return &pb.Receipt{}, err
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	// embed the IANA time zone database, as the Alpine container ships without one
+	_ "time/tzdata"
+)
+
+// offset_regexp matches UTC offsets such as -05:00, +0530, UTC+2 or GMT-3.
+var offset_regexp = regexp.MustCompile(`^(?:UTC|GMT)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// maxOffset is the largest UTC offset in use, UTC+14:00.
+const maxOffset = 14 * 60 * 60
+
+// loadLocation resolves a store time zone, given as an IANA name or a UTC offset.
+// An empty zone resolves to the fallback location.
+func loadLocation(zone string, fallback *time.Location) (loc *time.Location, err error) {
+	zone = strings.TrimSpace(zone)
+	switch strings.ToUpper(zone) {
+	case "":
+		return fallback, nil
+	case "Z", "UTC", "GMT":
+		return time.UTC, nil
+	}
+
+	if m := offset_regexp.FindStringSubmatch(strings.ToUpper(zone)); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if minutes >= 60 {
+			return nil, fmt.Errorf("time zone offset %s has invalid minutes", zone)
+		}
+		offset := hours*60*60 + minutes*60
+		if offset > maxOffset {
+			return nil, fmt.Errorf("time zone offset %s is out of range", zone)
+		}
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(fmt.Sprintf("UTC%s%02d:%02d", m[1], hours, minutes), offset), nil
+	}
+
+	// time.LoadLocation treats "Local" as the server's own zone, which is never the store's
+	if zone == "Local" {
+		return nil, fmt.Errorf("time zone %s is not a valid IANA time zone", zone)
+	}
+	return time.LoadLocation(zone)
+}
+
+// purchasedAt combines a validated canonical purchase date & time in the store's location.
+func purchasedAt(date string, t string, loc *time.Location) (at time.Time, err error) {
+	return time.ParseInLocation(CanonicalDate+" "+CanonicalTime, date+" "+t, loc)
+}
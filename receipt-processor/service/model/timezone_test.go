@@ -0,0 +1,114 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+func TestProcessor_ProcessReceipt_TimeZone(t *testing.T) {
+	type testCase struct {
+		zone        string
+		location    *time.Location
+		wantOffset  int
+		errExpected bool
+	}
+
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatalf("Error loading time zone data: %v", err)
+	}
+
+	var testCases = []testCase{
+		{zone: "", wantOffset: 0},
+		{zone: "UTC", wantOffset: 0},
+		{zone: "Z", wantOffset: 0},
+		{zone: "America/Chicago", wantOffset: -6 * 60 * 60},
+		{zone: "Asia/Kolkata", wantOffset: 5*60*60 + 30*60},
+		{zone: "-05:00", wantOffset: -5 * 60 * 60},
+		{zone: "+0530", wantOffset: 5*60*60 + 30*60},
+		{zone: "UTC+14", wantOffset: 14 * 60 * 60},
+		{zone: "gmt-3", wantOffset: -3 * 60 * 60},
+		// receipts without a zone fall back to the processor's location
+		{zone: "", location: chicago, wantOffset: -6 * 60 * 60},
+		{zone: "+01:00", location: chicago, wantOffset: 60 * 60},
+		{zone: "Mars/Olympus_Mons", errExpected: true},
+		{zone: "Local", errExpected: true},
+		{zone: "+15:00", errExpected: true},
+		{zone: "+05:75", errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		p := model.NewProcessor()
+		if tc.location != nil {
+			p.Location = tc.location
+		}
+
+		r := &pb.Receipt{
+			Retailer:     "TestTarget",
+			PurchaseDate: "2025-01-21",
+			PurchaseTime: "15:30",
+			Items: []*pb.Item{
+				{
+					ShortDescription: "An item at Target",
+					Price:            "40.29",
+				},
+			},
+			Total:    "40.29",
+			TimeZone: tc.zone,
+		}
+
+		rec, err := p.ProcessReceipt(context.Background(), r)
+		if err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error processing receipt in test case %d: %v", i+1, err)
+			continue
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+			continue
+		} else if tc.errExpected {
+			continue
+		}
+
+		// the printed date & time are the store's local date & time
+		if _, offset := rec.PurchasedAt.Zone(); offset != tc.wantOffset {
+			t.Errorf("Purchase time has the wrong UTC offset in test case %d: expected %d, received %d", i+1, tc.wantOffset, offset)
+		}
+		if rec.PurchasedAt.Hour() != 15 || rec.PurchasedAt.Minute() != 30 || rec.PurchasedAt.Day() != 21 {
+			t.Errorf("Purchase time is not the printed local time in test case %d: received %v", i+1, rec.PurchasedAt)
+		}
+	}
+}
+
+func Test_AwardPoints_LocalTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Error loading time zone data: %v", err)
+	}
+
+	// 3:30pm in New York is 8:30pm UTC
+	purchased := time.Date(2025, 1, 21, 15, 30, 0, 0, newYork)
+	r := &model.Receipt{
+		Retailer: "TestTarget",
+		Date:     "2025-01-21",
+		Time:     "15:30",
+		Total:    "40.29",
+		Items: []*model.Item{
+			{
+				ShortDescription: "An item at Target",
+				Price:            "40.29",
+			},
+		},
+		TimeZone:    "America/New_York",
+		PurchasedAt: purchased,
+	}
+	local := model.AwardPoints(r)
+
+	// the same instant, but evaluated in UTC, falls outside the 2pm-4pm window
+	r.PurchasedAt = purchased.UTC()
+	if utc := model.AwardPoints(r); local-utc != 10 {
+		t.Errorf("Purchase time window was not evaluated in the store's local time: expected 10 point difference, received %d", local-utc)
+	}
+}
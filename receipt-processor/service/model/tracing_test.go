@@ -0,0 +1,67 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+func TestProcessReceipt_Spans(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(sdktrace.NewTracerProvider()) })
+
+	receipt := &pb.Receipt{
+		Retailer:     "Target",
+		PurchaseDate: "2022-01-01",
+		PurchaseTime: "13:01",
+		Items:        []*pb.Item{{ShortDescription: "Mountain Dew 12PK", Price: "6.49"}},
+		Total:        "6.49",
+	}
+	rec, err := model.ProcessReceipt(context.Background(), receipt)
+	if err != nil {
+		t.Fatalf("Unexpected error processing receipt: %v", err)
+	}
+	db := &model.ReceiptDB{Store: make(map[string]*model.Receipt)}
+	if _, err := db.Get(context.Background(), "missing"); err == nil {
+		t.Fatalf("Did not receive expected error getting a missing receipt")
+	} else if _, err := db.Create(context.Background(), &rec); err != nil {
+		t.Fatalf("Unexpected error creating receipt: %v", err)
+	}
+
+	type testCase struct {
+		name   string
+		parent string
+		code   codes.Code
+	}
+
+	var testCases = []testCase{
+		{name: "model.validateReceipt", parent: "model.ProcessReceipt", code: codes.Unset},
+		{name: "model.ProcessReceipt", code: codes.Unset},
+		// failed store operations are marked as errors
+		{name: "ReceiptDB.Get", code: codes.Error},
+		{name: "ReceiptDB.Create", code: codes.Unset},
+	}
+
+	ended := spans.Ended()
+	if len(ended) != len(testCases) {
+		t.Fatalf("Wrong number of spans: expected %d, received %d", len(testCases), len(ended))
+	}
+	for i, tc := range testCases {
+		span := ended[i]
+		if span.Name() != tc.name {
+			t.Errorf("Wrong span in test case %d: expected %s, received %s", i+1, tc.name, span.Name())
+		} else if span.Status().Code != tc.code {
+			t.Errorf("Wrong status in test case %d: expected %s, received %s", i+1, tc.code, span.Status().Code)
+		} else if tc.parent != "" && span.Parent().SpanID() != ended[i+1].SpanContext().SpanID() {
+			t.Errorf("Wrong parent in test case %d: expected %s", i+1, tc.parent)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+func Test_ProcessReceipt_Unicode(t *testing.T) {
+	type testCase struct {
+		retailer     string
+		description  string
+		wantRetailer string
+		errExpected  bool
+	}
+
+	var testCases = []testCase{
+		{retailer: "Café Müller", description: "Schwarzwälder Kirschtorte", wantRetailer: "Café Müller"},
+		{retailer: "Bäckerei", description: "Brötchen", wantRetailer: "Bäckerei"},
+		// decomposed input is stored composed
+		{retailer: "Cafe\u0301 Mu\u0308ller", description: "Cre\u0300me bru\u0302le\u0301e", wantRetailer: "Caf\u00e9 M\u00fcller"},
+		{retailer: "Φούρνος", description: "Τυρόπιτα", wantRetailer: "Φούρνος"},
+		{retailer: "Пятёрочка", description: "Молоко 2-5", wantRetailer: "Пятёрочка"},
+		{retailer: "ローソン", description: "おにぎり", wantRetailer: "ローソン"},
+		{retailer: "كارفور", description: "خبز", wantRetailer: "كارفور"},
+		{retailer: "बिग बाज़ार", description: "चाय", wantRetailer: "बिग बाज़ार"},
+		{retailer: "M&M Corner Market", description: "Gatorade", wantRetailer: "M&M Corner Market"},
+		// punctuation & symbols are still rejected, in any script
+		{retailer: "Target 🎯", description: "Pepsi", errExpected: true},
+		{retailer: "Café «Müller»", description: "Kuchen", errExpected: true},
+		{retailer: "Bäckerei", description: "Brötchen!", errExpected: true},
+		{retailer: "ローソン。", description: "おにぎり", errExpected: true},
+		{retailer: "!Target", description: "Pepsi", errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		r := &pb.Receipt{
+			Retailer:     tc.retailer,
+			PurchaseDate: "2025-01-21",
+			PurchaseTime: "13:43",
+			Items: []*pb.Item{
+				{
+					ShortDescription: tc.description,
+					Price:            "4.50",
+				},
+			},
+			Total: "4.50",
+		}
+
+		rec, err := model.ProcessReceipt(context.Background(), r)
+		if err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error processing receipt in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		} else if err == nil && rec.Retailer != tc.wantRetailer {
+			t.Errorf("Retailer was not normalized in test case %d: expected %q, received %q", i+1, tc.wantRetailer, rec.Retailer)
+		}
+	}
+}
+
+func Test_AwardPoints_RetailerAlphanumerics(t *testing.T) {
+	type testCase struct {
+		retailer string
+		points   int64
+	}
+
+	var testCases = []testCase{
+		{retailer: "Target", points: 6},
+		{retailer: "M&M Corner Market", points: 14},
+		{retailer: "7-Eleven", points: 7},
+		{retailer: "Test_Target", points: 10},
+		{retailer: "Café Müller", points: 10},
+		{retailer: "Cafe\u0301 Mu\u0308ller", points: 10},
+		{retailer: "Bäckerei", points: 8},
+		{retailer: "Φούρνος", points: 7},
+		{retailer: "Пятёрочка", points: 9},
+		{retailer: "ローソン", points: 4},
+		{retailer: "كارفور", points: 6},
+		{retailer: "٧-إليفن", points: 6},
+		// Devanagari vowel signs are marks, not letters
+		{retailer: "बिग बाज़ार", points: 5},
+	}
+
+	for i, tc := range testCases {
+		r := &model.Receipt{
+			Retailer: tc.retailer,
+			Date:     "2025-01-21",
+			Time:     "13:43",
+			Total:    "4.51",
+			Items: []*model.Item{
+				{
+					ShortDescription: "Pepsi",
+					Price:            "4.51",
+				},
+			},
+		}
+		award := model.AwardPoints(r)
+		r.Retailer = ""
+		if baseline := model.AwardPoints(r); award-baseline != tc.points {
+			t.Errorf("Expected retailer points were not awarded in test case %d: expected %d, got %d", i+1, tc.points, award-baseline)
+		}
+	}
+}
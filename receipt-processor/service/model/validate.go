@@ -11,44 +11,51 @@ import (
 
 // regex
 var id_regexp = regexp.MustCompile(`^\S+$`)
-var retailer_regexp = regexp.MustCompile(`[\w\s\-&]+$`)
-var total_regexp = regexp.MustCompile(`^\d+\.\d{2}$`)
+
+// retailer & short description accept letters, marks & digits in any script, after NFC normalization
+var retailer_regexp = regexp.MustCompile(`^[\p{L}\p{M}\p{N}_\s\-&]+$`)
 var date_regexp = regexp.MustCompile(`^\d{4}\-(0?[1-9]|1[012])\-(0?[1-9]|[12][0-9]|3[01])$`)
-var shortDesc_regexp = regexp.MustCompile(`^[\w\s\-]+$`)
-var price_regexp = regexp.MustCompile(`^\d+\.\d{2}$`)
-
-var alphanumeric_regexp = regexp.MustCompile("^[a-zA-Z0-9_]*$")
-
-func validateReceipt(
-	retailer string,
-	date string,
-	time string,
-	total string,
-	items []*Item,
-) (err error) {
+var shortDesc_regexp = regexp.MustCompile(`^[\p{L}\p{M}\p{N}_\s\-]+$`)
+
+// validateReceipt checks each field's format, then each limit of the policy,
+// reporting every invalid field & violated limit together.
+func validateReceipt(r *Receipt, zoneErr error, policy *ValidationPolicy, now time.Time) (err error) {
 	invalid := make([]string, 0)
 
-	if _, e := validateField(retailer, retailer_regexp); e != nil {
+	if _, e := validateField(r.Retailer, retailer_regexp); e != nil {
 		invalid = append(invalid, "retailer")
 	}
 
-	if _, e := validateField(date, date_regexp); e != nil {
+	if _, e := validateDate(r.Date); e != nil {
 		invalid = append(invalid, "date")
 	}
 
-	if _, e := validateTime(time); e != nil {
+	if _, e := validateTime(r.Time); e != nil {
 		invalid = append(invalid, "time")
 	}
 
-	if _, e := validateItems(items); e != nil {
+	if zoneErr != nil {
+		invalid = append(invalid, "timeZone")
+	}
+
+	// the submitting user is optional, but must be a valid id when given
+	if r.UserID != "" && !id_regexp.MatchString(r.UserID) {
+		invalid = append(invalid, "userId")
+	}
+
+	// amounts can only be validated once we know how many decimals they should have
+	if c, ok := LookupCurrency(r.Currency); !ok {
+		invalid = append(invalid, "currency")
+	} else if _, e := validateItems(r.Items, c); e != nil {
 		invalid = append(invalid, "items")
 
-	} else if _, e := validateTotal(total, items); e != nil {
+	} else if _, e := validateTotal(r.Total, r.Items, c); e != nil {
 		invalid = append(invalid, "total")
 	}
 
-	if len(invalid) > 0 {
-		return ErrBadRequest(fmt.Sprintf("Receipt is invalid: fields are invalid %s", invalid))
+	violated := policy.violations(r, invalid, now)
+	if len(invalid) > 0 || len(violated) > 0 {
+		return &ValidationError{Invalid: invalid, Violated: violated}
 	} else {
 		return nil
 	}
@@ -62,8 +69,8 @@ func validateField(field string, regex *regexp.Regexp) (valid bool, err error) {
 	}
 }
 
-func validateTotal(total string, items []*Item) (valid bool, err error) {
-	if v := total_regexp.MatchString(total); !v {
+func validateTotal(total string, items []*Item, currency Currency) (valid bool, err error) {
+	if v := currency.amountRegexp().MatchString(total); !v {
 		return false, ErrBadRequest(fmt.Sprintf("Receipt total is invalid: %d", err))
 	} else {
 		var reconcile float64
@@ -82,7 +89,7 @@ func validateTotal(total string, items []*Item) (valid bool, err error) {
 	return true, nil
 }
 
-func validateItems(items []*Item) (valid bool, err error) {
+func validateItems(items []*Item, currency Currency) (valid bool, err error) {
 	ctrValidated := 0
 	for _, item := range items {
 		sv := shortDesc_regexp.MatchString(item.ShortDescription)
@@ -90,7 +97,7 @@ func validateItems(items []*Item) (valid bool, err error) {
 			return false, ErrInternalServer("Item short description is invalid for item")
 		}
 
-		pv := price_regexp.MatchString(item.Price)
+		pv := currency.amountRegexp().MatchString(item.Price)
 		if !pv {
 			return false, ErrBadRequest("Item Price is invalid")
 		}
@@ -106,6 +113,18 @@ func validateItems(items []*Item) (valid bool, err error) {
 	}
 }
 
+func validateDate(date string) (valid bool, err error) {
+	if _, err = validateField(date, date_regexp); err != nil {
+		return false, err
+	}
+	// the pattern admits impossible days, like February 30th
+	if _, err = time.Parse(CanonicalDate, date); err != nil {
+		return false, ErrBadRequest("Receipt Purchase Date could not be processed: " + err.Error())
+	} else {
+		return true, nil
+	}
+}
+
 func validateTime(t string) (valid bool, err error) {
 	// sanitize to HH:MM:SS
 	t = t + ":00"
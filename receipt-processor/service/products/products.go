@@ -0,0 +1,135 @@
+package products
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// A Dictionary lists the brands & category keywords items are classified by.
+type Dictionary struct {
+	Brands     []Brand             `json:"brands"`
+	Categories map[string][]string `json:"categories"` // Keywords, by the category they indicate.
+}
+
+// A Brand is a product brand, recognized by its name or aliases appearing in an item's description.
+type Brand struct {
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases"`
+	Category string   `json:"category"` // Optional. The category of items of this brand, when no keywords say otherwise.
+}
+
+// A Classifier identifies the brand & category of items, by the words in their normalized descriptions.
+type Classifier struct {
+	brands   []phrase // longest first, so "Mountain Dew Code Red" wins over "Mountain Dew"
+	keywords []phrase
+}
+
+// phrase is a sequence of words, & what it identifies.
+type phrase struct {
+	words    []string
+	brand    string
+	category string
+}
+
+// NewClassifier returns a Classifier for a dictionary.
+func NewClassifier(d Dictionary) (c *Classifier, err error) {
+	c = &Classifier{}
+	for _, b := range d.Brands {
+		if b.Name == "" {
+			return nil, fmt.Errorf("brand has no name")
+		} else if b.Category != "" && d.Categories[b.Category] == nil {
+			return nil, fmt.Errorf("brand %s has unknown category %s", b.Name, b.Category)
+		}
+		for _, name := range append([]string{b.Name}, b.Aliases...) {
+			if words := tokenize(name); len(words) == 0 {
+				return nil, fmt.Errorf("brand %s has a name without letters or digits: %q", b.Name, name)
+			} else {
+				c.brands = append(c.brands, phrase{words: words, brand: b.Name, category: b.Category})
+			}
+		}
+	}
+	for category, keywords := range d.Categories {
+		for _, keyword := range keywords {
+			if words := tokenize(keyword); len(words) == 0 {
+				return nil, fmt.Errorf("category %s has a keyword without letters or digits: %q", category, keyword)
+			} else {
+				c.keywords = append(c.keywords, phrase{words: words, category: category})
+			}
+		}
+	}
+	sort.SliceStable(c.brands, func(i, j int) bool { return len(c.brands[i].words) > len(c.brands[j].words) })
+	return c, nil
+}
+
+// LoadFile reads a JSON dictionary.
+func LoadFile(path string) (c *Classifier, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadJSON(f)
+}
+
+// LoadJSON reads a JSON dictionary.
+func LoadJSON(r io.Reader) (c *Classifier, err error) {
+	var d Dictionary
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("error decoding product dictionary: %w", err)
+	}
+	return NewClassifier(d)
+}
+
+// Classify returns the brand named in a normalized description, and the category whose keywords it mentions most.
+// Ties go to the alphabetically first category; descriptions without any keywords take their brand's category.
+func (c *Classifier) Classify(normalized string) (brand string, category string) {
+	words := tokenize(normalized)
+
+	for _, p := range c.brands {
+		if contains(words, p.words) {
+			brand, category = p.brand, p.category
+			break
+		}
+	}
+
+	hits := make(map[string]int)
+	for _, p := range c.keywords {
+		if contains(words, p.words) {
+			hits[p.category]++
+		}
+	}
+	best := 0
+	for cat, n := range hits {
+		if n > best || (n == best && cat < category) {
+			best, category = n, cat
+		}
+	}
+	return brand, category
+}
+
+// tokenize splits a description into its normalized words, so punctuation like "12-oz" or "Ben&Jerry's" separates words.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(model.NormalizeDescription(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// contains reports whether a sequence of words appears, in order & adjacent, within words.
+func contains(words []string, sequence []string) bool {
+	for i := 0; i+len(sequence) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(sequence)], sequence) {
+			return true
+		}
+	}
+	return false
+}
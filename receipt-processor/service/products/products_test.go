@@ -0,0 +1,92 @@
+package products_test
+
+import (
+	"strings"
+	"testing"
+
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/products"
+)
+
+func TestClassifier_Classify(t *testing.T) {
+	type testCase struct {
+		description  string
+		wantBrand    string
+		wantCategory string
+	}
+
+	var testCases = []testCase{
+		{description: "Mountain Dew 12PK", wantBrand: "Mountain Dew", wantCategory: "beverages"},
+		{description: "   Klarbrunn 12-PK 12 FL OZ  ", wantBrand: "Klarbrunn", wantCategory: "beverages"},
+		{description: "DORITOS Nacho 1.75oz", wantBrand: "Doritos", wantCategory: "snacks"},
+		// the longest brand named wins
+		{description: "Mountain Dew Code Red", wantBrand: "Mountain Dew Code Red", wantCategory: "beverages"},
+		// keywords outweigh the brand's usual category, & the most mentioned category wins
+		{description: "Emils Cheese Pizza", wantBrand: "Emils", wantCategory: "dairy"},
+		{description: "Emils Frozen Cheese Pizza", wantBrand: "Emils", wantCategory: "frozen"},
+		// multi-word keywords must appear together
+		{description: "Paper Towels 6 Rolls", wantBrand: "", wantCategory: "household"},
+		{description: "Towels Paper", wantBrand: "", wantCategory: ""},
+		// brands & keywords are whole words, so "Tide" isn't found in "Tidewater"
+		{description: "Tidewater Apples", wantBrand: "", wantCategory: "produce"},
+		{description: "Gift Card", wantBrand: "", wantCategory: ""},
+	}
+
+	c, err := products.NewClassifier(products.Dictionary{
+		Brands: []products.Brand{
+			{Name: "Mountain Dew", Category: "beverages"},
+			{Name: "Mountain Dew Code Red", Category: "beverages"},
+			{Name: "Klarbrunn", Category: "beverages"},
+			{Name: "Doritos", Category: "snacks"},
+			{Name: "Emils", Category: "frozen"},
+			{Name: "Tide", Category: "household"},
+		},
+		Categories: map[string][]string{
+			"beverages": {"12pk", "12 pk", "fl oz"},
+			"snacks":    {"chips"},
+			"frozen":    {"frozen", "pizza"},
+			"dairy":     {"cheese", "milk"},
+			"household": {"paper towels"},
+			"produce":   {"apples"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating classifier: %v", err)
+	}
+
+	for i, tc := range testCases {
+		brand, category := c.Classify(model.NormalizeDescription(tc.description))
+		if brand != tc.wantBrand || category != tc.wantCategory {
+			t.Errorf("Wrong classification in test case %d: expected %q & %q, received %q & %q", i+1, tc.wantBrand, tc.wantCategory, brand, category)
+		}
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	type testCase struct {
+		json        string
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{json: `{"brands": [{"name": "Pepsi", "category": "beverages"}], "categories": {"beverages": ["soda"]}}`},
+		{json: `{"brands": [], "categories": {}}`},
+		{json: `{"brands": [{"name": "Pepsi", "category": "snacks"}], "categories": {"beverages": ["soda"]}}`, errExpected: true},
+		{json: `{"brands": [{"name": ""}], "categories": {}}`, errExpected: true},
+		{json: `{"brands": [], "categories": {"beverages": ["--"]}}`, errExpected: true},
+		{json: `{"brands": [], "departments": {}}`, errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		if _, err := products.LoadJSON(strings.NewReader(tc.json)); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error loading dictionary in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+
+	// the dictionary shipped with the service must load
+	if _, err := products.LoadFile("../../data/products.json"); err != nil {
+		t.Errorf("Unexpected error loading the service's dictionary: %v", err)
+	}
+}
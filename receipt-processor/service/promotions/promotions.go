@@ -0,0 +1,185 @@
+package promotions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+)
+
+// A Promotion awards points on top of a receipt's base score, for purchases at a retailer
+// (& optionally of an item) within a range of purchase dates.
+type Promotion struct {
+	ID          string  `json:"id"`
+	Description string  `json:"description"` // Shown to users alongside the points awarded, e.g. "2x points at Walgreens this weekend".
+	Retailer    string  `json:"retailer"`    // The retailer's canonical id, so receipts naming any of its aliases match; or a name, for unknown retailers.
+	Item        string  `json:"item"`        // Optional. Text an item's short description must contain, ignoring case & spacing.
+	Multiplier  float64 `json:"multiplier"`  // Optional. Multiplies the base score, e.g. 2 for double points.
+	Bonus       int64   `json:"bonus"`       // Optional. Flat points added to the receipt.
+	Starts      string  `json:"starts"`      // The first purchase date (YYYY-MM-DD) the promotion applies to.
+	Ends        string  `json:"ends"`        // The last purchase date (YYYY-MM-DD) the promotion applies to.
+	Cap         int64   `json:"cap"`         // Optional. Most points awarded per receipt.
+	Budget      int64   `json:"budget"`      // Optional. Most points awarded across all receipts.
+}
+
+// An Award is the points a single promotion added to a receipt's score.
+type Award struct {
+	PromotionID string
+	Description string
+	Points      int64
+}
+
+// A Catalog holds the promotions being run, & the points each has awarded so far. A nil Catalog runs none.
+type Catalog struct {
+	promotions []Promotion
+	awarded    map[string]int64 // points awarded, by promotion id
+	sync.Mutex
+}
+
+// NewCatalog returns a Catalog running the given promotions, which must be valid.
+func NewCatalog(promotions ...Promotion) (c *Catalog, err error) {
+	c = &Catalog{awarded: make(map[string]int64)}
+	ids := make(map[string]bool)
+	for _, p := range promotions {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		} else if ids[p.ID] {
+			return nil, fmt.Errorf("promotion %s is defined more than once", p.ID)
+		}
+		ids[p.ID] = true
+		c.promotions = append(c.promotions, p)
+	}
+	return c, nil
+}
+
+// LoadFile reads a JSON array of promotions.
+func LoadFile(path string) (c *Catalog, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadJSON(f)
+}
+
+// LoadJSON reads a JSON array of promotions.
+func LoadJSON(r io.Reader) (c *Catalog, err error) {
+	var promotions []Promotion
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&promotions); err != nil {
+		return nil, fmt.Errorf("error decoding promotions: %w", err)
+	}
+	return NewCatalog(promotions...)
+}
+
+// Validate reports configuration errors in the promotion.
+func (p *Promotion) Validate() (err error) {
+	if p.ID == "" {
+		return fmt.Errorf("promotion has no id")
+	} else if matchKey(p.Retailer) == "" {
+		return fmt.Errorf("promotion %s has no retailer", p.ID)
+	} else if p.Multiplier < 0 || p.Bonus < 0 || p.Cap < 0 || p.Budget < 0 {
+		return fmt.Errorf("promotion %s has a negative multiplier, bonus, cap or budget", p.ID)
+	} else if p.Multiplier <= 1 && p.Bonus == 0 {
+		return fmt.Errorf("promotion %s awards no points: it needs a multiplier above 1 or a bonus", p.ID)
+	}
+
+	starts, err := time.Parse(model.CanonicalDate, p.Starts)
+	if err != nil {
+		return fmt.Errorf("promotion %s has an invalid start date: %w", p.ID, err)
+	}
+	ends, err := time.Parse(model.CanonicalDate, p.Ends)
+	if err != nil {
+		return fmt.Errorf("promotion %s has an invalid end date: %w", p.ID, err)
+	} else if ends.Before(starts) {
+		return fmt.Errorf("promotion %s ends before it starts", p.ID)
+	}
+	return nil
+}
+
+// Apply returns the points each matching promotion adds to a receipt's base score,
+// reserving them against the promotions' budgets until they're refunded.
+func (c *Catalog) Apply(r *model.Receipt, base int64) (awards []Award) {
+	awards = make([]Award, 0)
+	if c == nil {
+		return awards
+	}
+	c.Lock()
+	defer c.Unlock()
+	for _, p := range c.promotions {
+		if !p.matches(r) {
+			continue
+		}
+		points := p.points(base)
+		if p.Budget > 0 {
+			points = min(points, p.Budget-c.awarded[p.ID])
+		}
+		if points > 0 {
+			c.awarded[p.ID] += points
+			awards = append(awards, Award{PromotionID: p.ID, Description: p.Description, Points: points})
+		}
+	}
+	return awards
+}
+
+// Refund returns the points reserved by awards which weren't credited after all, such as when the receipt was
+// awarded concurrently, to the promotions' budgets.
+func (c *Catalog) Refund(awards []Award) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for _, a := range awards {
+		c.awarded[a.PromotionID] = max(c.awarded[a.PromotionID]-a.Points, 0)
+	}
+}
+
+// matches reports whether a receipt qualifies for the promotion.
+func (p *Promotion) matches(r *model.Receipt) bool {
+	// canonical dates sort chronologically
+	if r.Date < p.Starts || r.Date > p.Ends {
+		return false
+	} else if matchKey(r.RetailerID) != matchKey(p.Retailer) && matchKey(r.Retailer) != matchKey(p.Retailer) {
+		return false
+	} else if p.Item == "" {
+		return true
+	}
+	for _, item := range r.Items {
+		if strings.Contains(model.NormalizeDescription(item.ShortDescription), model.NormalizeDescription(p.Item)) {
+			return true
+		}
+	}
+	return false
+}
+
+// points returns the promotion's points for a receipt with the given base score, up to its cap.
+func (p *Promotion) points(base int64) (points int64) {
+	if p.Multiplier > 1 {
+		points = int64(math.Round(float64(base) * (p.Multiplier - 1)))
+	}
+	points += p.Bonus
+	if p.Cap > 0 {
+		points = min(points, p.Cap)
+	}
+	return points
+}
+
+// matchKey reduces a retailer's name to its lowercase letters & digits, so "M&M Corner Market" matches "m & m corner market".
+func matchKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package promotions_test
+
+import (
+	"strings"
+	"testing"
+
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/promotions"
+)
+
+var doublePoints = promotions.Promotion{
+	ID:          "walgreens-weekend",
+	Description: "2x points at Walgreens this weekend",
+	Retailer:    "Walgreens",
+	Multiplier:  2,
+	Starts:      "2025-01-18",
+	Ends:        "2025-01-19",
+}
+
+var gatorade = promotions.Promotion{
+	ID:          "gatorade",
+	Description: "+500 for buying Gatorade",
+	Retailer:    "Walgreens",
+	Item:        "gatorade",
+	Bonus:       500,
+	Starts:      "2025-01-01",
+	Ends:        "2025-01-31",
+}
+
+func receipt(retailer string, date string, items ...string) *model.Receipt {
+	r := &model.Receipt{Retailer: retailer, Date: date, Time: "13:43", Total: "10.00", Items: make([]*model.Item, 0)}
+	for _, item := range items {
+		r.Items = append(r.Items, &model.Item{ShortDescription: item, Price: "10.00"})
+	}
+	return r
+}
+
+func matched(r *model.Receipt, retailerID string) *model.Receipt {
+	r.RetailerID = retailerID
+	return r
+}
+
+func TestCatalog_Apply(t *testing.T) {
+	type testCase struct {
+		promotions []promotions.Promotion
+		receipt    *model.Receipt
+		base       int64
+		wantPoints []int64 // points awarded by each applied promotion, in catalog order
+	}
+
+	capped := doublePoints
+	capped.Cap = 50
+	byID := doublePoints
+	byID.Retailer = "walgreens"
+	roundedUp := doublePoints
+	roundedUp.Multiplier = 1.5
+
+	var testCases = []testCase{
+		{promotions: []promotions.Promotion{doublePoints}, receipt: receipt("Walgreens", "2025-01-18", "Chips"), base: 100, wantPoints: []int64{100}},
+		// start & end dates are inclusive
+		{promotions: []promotions.Promotion{doublePoints}, receipt: receipt("Walgreens", "2025-01-19", "Chips"), base: 100, wantPoints: []int64{100}},
+		{promotions: []promotions.Promotion{doublePoints}, receipt: receipt("Walgreens", "2025-01-20", "Chips"), base: 100, wantPoints: []int64{}},
+		{promotions: []promotions.Promotion{doublePoints}, receipt: receipt("Walgreens", "2025-01-17", "Chips"), base: 100, wantPoints: []int64{}},
+		// retailers are matched ignoring case, spacing & punctuation
+		{promotions: []promotions.Promotion{doublePoints}, receipt: receipt("WALGREENS ", "2025-01-18", "Chips"), base: 100, wantPoints: []int64{100}},
+		{promotions: []promotions.Promotion{doublePoints}, receipt: receipt("Target", "2025-01-18", "Chips"), base: 100, wantPoints: []int64{}},
+		// or by the canonical id of the retailer the receipt was matched to
+		{promotions: []promotions.Promotion{byID}, receipt: matched(receipt("Walgreen Co #4412", "2025-01-18", "Chips"), "walgreens"), base: 100, wantPoints: []int64{100}},
+		{promotions: []promotions.Promotion{byID}, receipt: receipt("Walgreen Co #4412", "2025-01-18", "Chips"), base: 100, wantPoints: []int64{}},
+		{promotions: []promotions.Promotion{doublePoints}, receipt: matched(receipt("WALGREENS PHARMACY", "2025-01-18", "Chips"), "walgreens"), base: 100, wantPoints: []int64{100}},
+		// item promotions need a matching item
+		{promotions: []promotions.Promotion{gatorade}, receipt: receipt("Walgreens", "2025-01-10", "Chips", "GATORADE Cool Blue"), base: 100, wantPoints: []int64{500}},
+		{promotions: []promotions.Promotion{gatorade}, receipt: receipt("Walgreens", "2025-01-10", "Chips"), base: 100, wantPoints: []int64{}},
+		// promotions stack, each computed on the base score
+		{promotions: []promotions.Promotion{doublePoints, gatorade}, receipt: receipt("Walgreens", "2025-01-18", "Gatorade"), base: 80, wantPoints: []int64{80, 500}},
+		{promotions: []promotions.Promotion{capped}, receipt: receipt("Walgreens", "2025-01-18", "Chips"), base: 100, wantPoints: []int64{50}},
+		{promotions: []promotions.Promotion{roundedUp}, receipt: receipt("Walgreens", "2025-01-18", "Chips"), base: 25, wantPoints: []int64{13}},
+		// nothing is awarded on a zero base score
+		{promotions: []promotions.Promotion{doublePoints}, receipt: receipt("Walgreens", "2025-01-18", "Chips"), base: 0, wantPoints: []int64{}},
+	}
+
+	for i, tc := range testCases {
+		c, err := promotions.NewCatalog(tc.promotions...)
+		if err != nil {
+			t.Fatalf("Unexpected error creating catalog in test case %d: %v", i+1, err)
+		}
+		awards := c.Apply(tc.receipt, tc.base)
+		if len(awards) != len(tc.wantPoints) {
+			t.Errorf("Wrong promotions applied in test case %d: expected %v, received %+v", i+1, tc.wantPoints, awards)
+			continue
+		}
+		for j, want := range tc.wantPoints {
+			if awards[j].Points != want {
+				t.Errorf("Wrong promotion points in test case %d: expected %d, received %d", i+1, want, awards[j].Points)
+			}
+		}
+	}
+}
+
+func TestCatalog_Apply_Budget(t *testing.T) {
+	limited := gatorade
+	limited.Budget = 1200
+	c, err := promotions.NewCatalog(limited)
+	if err != nil {
+		t.Fatalf("Unexpected error creating catalog: %v", err)
+	}
+
+	// the budget is spent across receipts, with the last award cut short
+	var awarded []int64
+	for i := 0; i < 4; i++ {
+		var points int64
+		for _, a := range c.Apply(receipt("Walgreens", "2025-01-10", "Gatorade"), 10) {
+			points += a.Points
+		}
+		awarded = append(awarded, points)
+	}
+	if awarded[0] != 500 || awarded[1] != 500 || awarded[2] != 200 || awarded[3] != 0 {
+		t.Errorf("Promotion budget was not respected: received %v", awarded)
+	}
+
+	// refunded awards return to the budget
+	c.Refund([]promotions.Award{{PromotionID: limited.ID, Points: 300}})
+	if awards := c.Apply(receipt("Walgreens", "2025-01-10", "Gatorade"), 10); len(awards) != 1 || awards[0].Points != 300 {
+		t.Errorf("Refunded budget was not awarded: received %+v", awards)
+	}
+}
+
+func TestCatalog_Nil(t *testing.T) {
+	var c *promotions.Catalog
+	if awards := c.Apply(receipt("Walgreens", "2025-01-10", "Gatorade"), 10); len(awards) != 0 {
+		t.Errorf("Nil catalog applied promotions: %+v", awards)
+	}
+	c.Refund([]promotions.Award{{PromotionID: gatorade.ID, Points: 500}})
+}
+
+func TestLoadJSON(t *testing.T) {
+	type testCase struct {
+		json        string
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{json: `[{"id": "a", "retailer": "Target", "multiplier": 2, "starts": "2025-01-01", "ends": "2025-01-31"}]`},
+		{json: `[]`},
+		{json: `[{"id": "a", "retailer": "Target", "starts": "2025-01-01", "ends": "2025-01-31"}]`, errExpected: true},
+		{json: `[{"id": "a", "retailer": "Target", "bonus": 5, "starts": "2025-01-31", "ends": "2025-01-01"}]`, errExpected: true},
+		{json: `[{"id": "a", "retailer": "Target", "bonus": 5, "starts": "01/01/2025", "ends": "2025-01-31"}]`, errExpected: true},
+		{json: `[{"id": "a", "retailer": "", "bonus": 5, "starts": "2025-01-01", "ends": "2025-01-31"}]`, errExpected: true},
+		{json: `[{"id": "a", "retailer": "Target", "bonus": -5, "starts": "2025-01-01", "ends": "2025-01-31"}]`, errExpected: true},
+		{json: `[{"id": "a", "retailer": "Target", "bonus": 5, "starts": "2025-01-01", "ends": "2025-01-31"},
+		         {"id": "a", "retailer": "Walgreens", "bonus": 5, "starts": "2025-01-01", "ends": "2025-01-31"}]`, errExpected: true},
+		{json: `[{"id": "a", "store": "Target", "bonus": 5, "starts": "2025-01-01", "ends": "2025-01-31"}]`, errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		if _, err := promotions.LoadJSON(strings.NewReader(tc.json)); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error loading promotions in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+}
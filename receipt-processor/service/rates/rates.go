@@ -0,0 +1,170 @@
+package rates
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRateNotFound is returned when no usable rate exists for a currency on a date.
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// A Table is a dated exchange-rate table, converting amounts into a single base currency.
+// Rates are keyed by calendar date; a date without a rate falls back to the most recent earlier rate,
+// or, when backfilling, a date before a currency's first rate uses that first rate.
+type Table struct {
+	Base     string        // The ISO 4217 code of the currency all rates convert into.
+	MaxAge   time.Duration // How stale a fallback rate may be; zero allows any earlier rate.
+	Backfill bool          // Whether dates before a currency's first rate use it, rather than having no rate.
+	rates    map[string][]datedRate
+}
+
+// datedRate is the value of one unit of a currency in the base currency, on a date.
+type datedRate struct {
+	date time.Time
+	rate float64
+}
+
+// NewTable returns an empty Table converting into the base currency.
+func NewTable(base string) *Table {
+	return &Table{Base: strings.ToUpper(base), rates: make(map[string][]datedRate)}
+}
+
+// Add records the value of one unit of currency in the base currency on a date.
+// A later Add for the same currency & date replaces the earlier rate.
+func (t *Table) Add(currency string, date time.Time, rate float64) error {
+	if rate <= 0 {
+		return fmt.Errorf("rate for %s on %s must be positive", currency, date.Format(time.DateOnly))
+	}
+	currency = strings.ToUpper(currency)
+	date = day(date)
+
+	rates := t.rates[currency]
+	i := sort.Search(len(rates), func(i int) bool { return !rates[i].date.Before(date) })
+	if i < len(rates) && rates[i].date.Equal(date) {
+		rates[i].rate = rate
+		return nil
+	}
+	rates = append(rates, datedRate{})
+	copy(rates[i+1:], rates[i:])
+	rates[i] = datedRate{date: date, rate: rate}
+	t.rates[currency] = rates
+	return nil
+}
+
+// Rate returns the value of one unit of currency in the base currency on the calendar date of on,
+// in on's own time zone. When that date has no rate, the most recent earlier rate within MaxAge is used,
+// or the currency's first rate when there's none earlier & the table backfills.
+func (t *Table) Rate(currency string, on time.Time) (rate float64, err error) {
+	currency = strings.ToUpper(currency)
+	if currency == t.Base {
+		return 1, nil
+	}
+
+	date := day(on)
+	rates := t.rates[currency]
+	// the first rate dated after the purchase date; the one before it is the latest usable rate
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].date.After(date) })
+	if i == 0 && t.Backfill && len(rates) > 0 {
+		return rates[0].rate, nil
+	} else if i == 0 {
+		return 0, fmt.Errorf("%w: no %s rate on or before %s", ErrRateNotFound, currency, date.Format(time.DateOnly))
+	}
+	found := rates[i-1]
+	if t.MaxAge > 0 && date.Sub(found.date) > t.MaxAge {
+		return 0, fmt.Errorf("%w: latest %s rate before %s is from %s", ErrRateNotFound, currency, date.Format(time.DateOnly), found.date.Format(time.DateOnly))
+	}
+	return found.rate, nil
+}
+
+// LoadFile reads a rate table from a .csv or .json file; see LoadCSV & LoadJSON for their formats.
+func LoadFile(path string, base string) (t *Table, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return LoadCSV(f, base)
+	case ".json":
+		return LoadJSON(f, base)
+	default:
+		return nil, fmt.Errorf("rate table %s must be a .csv or .json file", path)
+	}
+}
+
+// LoadCSV reads a rate table with a date,currency,rate header, one rate per row.
+// Dates are YYYY-MM-DD, and lines beginning with # are comments.
+func LoadCSV(r io.Reader, base string) (t *Table, err error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 3
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("rate table header could not be read: %w", err)
+	} else if strings.Join(header, ",") != "date,currency,rate" {
+		return nil, fmt.Errorf("rate table header must be date,currency,rate, found %s", strings.Join(header, ","))
+	}
+
+	t = NewTable(base)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return t, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("rate table could not be read: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		if err := t.addRecord(record[0], record[1], record[2]); err != nil {
+			return nil, fmt.Errorf("rate table line %d: %w", line, err)
+		}
+	}
+}
+
+// LoadJSON reads a rate table keyed by YYYY-MM-DD date, then currency, e.g.
+// {"2025-01-21": {"CAD": 0.6952, "GBP": 1.2315}}
+func LoadJSON(r io.Reader, base string) (t *Table, err error) {
+	var dated map[string]map[string]json.Number
+	if err := json.NewDecoder(r).Decode(&dated); err != nil {
+		return nil, fmt.Errorf("rate table could not be read: %w", err)
+	}
+
+	t = NewTable(base)
+	for date, rates := range dated {
+		for currency, rate := range rates {
+			if err := t.addRecord(date, currency, rate.String()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t, nil
+}
+
+func (t *Table) addRecord(date string, currency string, rate string) error {
+	d, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return fmt.Errorf("date %q is not YYYY-MM-DD", date)
+	}
+	r, err := strconv.ParseFloat(rate, 64)
+	if err != nil {
+		return fmt.Errorf("rate %q for %s on %s is not a number", rate, currency, date)
+	}
+	return t.Add(currency, d, r)
+}
+
+// day truncates a time to its calendar date, in its own time zone.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
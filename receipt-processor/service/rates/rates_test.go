@@ -0,0 +1,113 @@
+package rates_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/rates"
+)
+
+func TestTable_Rate(t *testing.T) {
+	type testCase struct {
+		currency    string
+		on          time.Time
+		maxAge      time.Duration
+		backfill    bool
+		rate        float64
+		errExpected bool
+	}
+
+	tokyo := time.FixedZone("UTC+09:00", 9*60*60)
+
+	var testCases = []testCase{
+		// the base currency never needs a rate
+		{currency: "USD", on: date(2020, 1, 1), rate: 1},
+		{currency: "usd", on: date(2020, 1, 1), rate: 1},
+		// exact dates
+		{currency: "CAD", on: date(2025, 1, 21), rate: 0.69},
+		{currency: "cad", on: date(2025, 1, 24), rate: 0.68},
+		{currency: "GBP", on: date(2025, 1, 27), rate: 1.25},
+		// missing dates fall back to the most recent earlier rate
+		{currency: "CAD", on: date(2025, 1, 23), rate: 0.69},
+		{currency: "CAD", on: date(2025, 3, 1), rate: 0.68},
+		{currency: "GBP", on: date(2025, 1, 26), rate: 1.23},
+		// ...unless it is older than the maximum age
+		{currency: "CAD", on: date(2025, 1, 23), maxAge: 48 * time.Hour, rate: 0.69},
+		{currency: "CAD", on: date(2025, 1, 23), maxAge: 24 * time.Hour, errExpected: true},
+		{currency: "CAD", on: date(2025, 3, 1), maxAge: 7 * 24 * time.Hour, errExpected: true},
+		// the purchase date is the store's local date, not the UTC date
+		{currency: "CAD", on: time.Date(2025, 1, 21, 8, 0, 0, 0, tokyo), rate: 0.69},
+		// there is nothing earlier to fall back to
+		{currency: "CAD", on: date(2025, 1, 19), errExpected: true},
+		{currency: "GBP", on: date(2025, 1, 20), errExpected: true},
+		// ...unless the table backfills with the first rate
+		{currency: "CAD", on: date(2025, 1, 19), backfill: true, rate: 0.7},
+		{currency: "GBP", on: date(2020, 1, 1), backfill: true, rate: 1.23},
+		{currency: "EUR", on: date(2025, 1, 21), backfill: true, errExpected: true},
+		// unknown currencies
+		{currency: "EUR", on: date(2025, 1, 21), errExpected: true},
+	}
+
+	for _, file := range []string{"rates.csv", "rates.json"} {
+		table, err := rates.LoadFile(filepath.Join("testdata", file), "USD")
+		if err != nil {
+			t.Fatalf("Error loading rate table %s: %v", file, err)
+		}
+
+		for i, tc := range testCases {
+			table.MaxAge = tc.maxAge
+			table.Backfill = tc.backfill
+			rate, err := table.Rate(tc.currency, tc.on)
+			if err != nil && !tc.errExpected {
+				t.Errorf("Unexpected error getting rate from %s in test case %d: %v", file, i+1, err)
+			} else if err == nil && tc.errExpected {
+				t.Errorf("Did not receive expected error getting rate from %s in test case %d", file, i+1)
+			} else if err != nil && !errors.Is(err, rates.ErrRateNotFound) {
+				t.Errorf("Error getting rate from %s in test case %d is not ErrRateNotFound: %v", file, i+1, err)
+			} else if err == nil && rate != tc.rate {
+				t.Errorf("Wrong rate from %s in test case %d: expected %v, received %v", file, i+1, tc.rate, rate)
+			}
+		}
+	}
+}
+
+func TestLoadFile_Shipped(t *testing.T) {
+	table, err := rates.LoadFile("../../data/rates.csv", "USD")
+	if err != nil {
+		t.Fatalf("Error loading shipped rate table: %v", err)
+	}
+	// every currency receipts may be paid in has a rate, however old or new the receipt
+	for code := range model.Currencies {
+		for _, on := range []time.Time{date(2020, 1, 1), date(2022, 5, 15), time.Now()} {
+			if _, err := table.Rate(code, on); err != nil {
+				t.Errorf("Unexpected error getting %s rate on %s: %v", code, on.Format(time.DateOnly), err)
+			}
+		}
+	}
+}
+
+func TestLoadCSV_Malformed(t *testing.T) {
+	type testCase = string
+
+	var testCases = []testCase{
+		"",
+		"day,currency,rate\n2025-01-21,CAD,0.69\n",
+		"date,currency,rate\n01/21/2025,CAD,0.69\n",
+		"date,currency,rate\n2025-01-21,CAD,lots\n",
+		"date,currency,rate\n2025-01-21,CAD,-1\n",
+		"date,currency,rate\n2025-01-21,CAD\n",
+	}
+	for i, tc := range testCases {
+		if _, err := rates.LoadCSV(strings.NewReader(tc), "USD"); err == nil {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
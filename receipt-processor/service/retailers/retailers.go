@@ -0,0 +1,182 @@
+package retailers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// A Retailer is a store known by a canonical id, however its name is printed on receipts.
+type Retailer struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`     // The name displayed to users.
+	Aliases  []string `json:"aliases"`  // Other names the retailer's receipts are printed with.
+	Category string   `json:"category"` // e.g. grocery, pharmacy or convenience.
+}
+
+// DefaultMaxDistance is the share of a name's characters which may differ from a known name, for the two to match.
+const DefaultMaxDistance = 0.2
+
+// minFuzzyLength is the shortest name matched approximately; shorter names differ too much with each character.
+const minFuzzyLength = 6
+
+// A Registry resolves the names printed on receipts to known retailers.
+type Registry struct {
+	MaxDistance float64 // Share of characters which may differ for an approximate match; zero only matches exactly.
+	retailers   map[string]Retailer
+	keys        map[string]string // retailer ids, by the match key of each name & alias
+}
+
+// NewRegistry returns a Registry of the given retailers, matching approximately within DefaultMaxDistance.
+// Names & aliases which reduce to the same match key must belong to the same retailer.
+func NewRegistry(retailers ...Retailer) (r *Registry, err error) {
+	r = &Registry{MaxDistance: DefaultMaxDistance, retailers: make(map[string]Retailer), keys: make(map[string]string)}
+	for _, retailer := range retailers {
+		if retailer.ID == "" || retailer.Name == "" {
+			return nil, fmt.Errorf("retailer %q needs both an id & a name", retailer.ID+retailer.Name)
+		} else if _, exists := r.retailers[retailer.ID]; exists {
+			return nil, fmt.Errorf("retailer %s is defined more than once", retailer.ID)
+		}
+		r.retailers[retailer.ID] = retailer
+
+		for _, name := range append([]string{retailer.Name}, retailer.Aliases...) {
+			key := matchKey(name)
+			if key == "" {
+				return nil, fmt.Errorf("retailer %s has a name without letters or digits: %q", retailer.ID, name)
+			} else if id, exists := r.keys[key]; exists && id != retailer.ID {
+				return nil, fmt.Errorf("retailers %s & %s both match %q", id, retailer.ID, name)
+			}
+			r.keys[key] = retailer.ID
+		}
+	}
+	return r, nil
+}
+
+// LoadFile reads a JSON array of retailers.
+func LoadFile(path string) (r *Registry, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadJSON(f)
+}
+
+// LoadJSON reads a JSON array of retailers.
+func LoadJSON(in io.Reader) (r *Registry, err error) {
+	var retailers []Retailer
+	dec := json.NewDecoder(in)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&retailers); err != nil {
+		return nil, fmt.Errorf("error decoding retailers: %w", err)
+	}
+	return NewRegistry(retailers...)
+}
+
+// Lookup returns the retailer with a canonical id.
+func (r *Registry) Lookup(id string) (retailer Retailer, ok bool) {
+	retailer, ok = r.retailers[id]
+	return retailer, ok
+}
+
+// Match returns the retailer a printed name refers to: exactly, once case, spacing, punctuation, accents & common
+// abbreviations are disregarded, or else approximately, when a single retailer is within MaxDistance of the name.
+func (r *Registry) Match(name string) (retailer Retailer, ok bool) {
+	key := matchKey(name)
+	if id, exists := r.keys[key]; exists {
+		return r.retailers[id], true
+	} else if utf8.RuneCountInString(key) < minFuzzyLength || r.MaxDistance <= 0 {
+		return Retailer{}, false
+	}
+
+	best, bestID, ambiguous := -1, "", false
+	for known, id := range r.keys {
+		d := distance(key, known)
+		if float64(d) > r.MaxDistance*float64(max(utf8.RuneCountInString(key), utf8.RuneCountInString(known))) {
+			continue
+		} else if best < 0 || d < best {
+			best, bestID, ambiguous = d, id, false
+		} else if d == best && id != bestID {
+			ambiguous = true
+		}
+	}
+	if best < 0 || ambiguous {
+		return Retailer{}, false
+	}
+	return r.retailers[bestID], true
+}
+
+// Resolve returns the canonical id of the retailer a printed name refers to.
+func (r *Registry) Resolve(name string) (id string, ok bool) {
+	retailer, ok := r.Match(name)
+	return retailer.ID, ok
+}
+
+// abbreviations are expanded before names are compared, so "M&M Corner Mkt" matches "M&M Corner Market".
+var abbreviations = map[string]string{
+	"bros": "brothers",
+	"co":   "company",
+	"corp": "corporation",
+	"ctr":  "center",
+	"intl": "international",
+	"mkt":  "market",
+	"mkts": "markets",
+	"svc":  "service",
+	"whse": "warehouse",
+}
+
+// fillers are disregarded when names are compared, so "M and M" matches "M&M".
+var fillers = map[string]bool{"and": true, "the": true}
+
+// matchKey reduces a name to the lowercase, unaccented letters & digits of its words, with abbreviations expanded.
+func matchKey(name string) string {
+	// decompose, so accents can be dropped as the marks they decompose to
+	words := strings.FieldsFunc(strings.ToLower(norm.NFKD.String(name)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+
+	var b strings.Builder
+	for _, word := range words {
+		word = strings.Map(func(r rune) rune {
+			if unicode.Is(unicode.Mn, r) {
+				return -1
+			}
+			return r
+		}, word)
+		if fillers[word] {
+			continue
+		} else if expanded, ok := abbreviations[word]; ok {
+			word = expanded
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+// distance returns the Levenshtein distance between two strings, in runes.
+func distance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,101 @@
+package retailers_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/retailers"
+)
+
+func TestRegistry_Match(t *testing.T) {
+	type testCase struct {
+		name   string
+		wantID string // empty when no retailer should match
+	}
+
+	var testCases = []testCase{
+		// case, spacing, punctuation & abbreviations are disregarded
+		{name: "M&M Corner Market", wantID: "m-and-m-corner-market"},
+		{name: "M & M CORNER MKT", wantID: "m-and-m-corner-market"},
+		{name: "m&m corner market", wantID: "m-and-m-corner-market"},
+		{name: "M and M Corner Market", wantID: "m-and-m-corner-market"},
+		{name: "M&M Corner Store", wantID: "m-and-m-corner-market"},
+		{name: "  TARGET  ", wantID: "target"},
+		{name: "Target.com", wantID: "target"},
+		{name: "Café Léa", wantID: "cafe-lea"},
+		// small misprints are matched approximately
+		{name: "Walgreen", wantID: "walgreens"},
+		{name: "Walgrens Pharmacy", wantID: "walgreens"},
+		{name: "M&M Coner Market", wantID: "m-and-m-corner-market"},
+		// short names only match exactly
+		{name: "CVX", wantID: ""},
+		{name: "CVS", wantID: "cvs"},
+		// names too far from any retailer, or equally near two, are unknown
+		{name: "Whole Foods", wantID: ""},
+		{name: "Corner Shop 1", wantID: ""},
+		{name: "", wantID: ""},
+	}
+
+	r, err := retailers.NewRegistry(
+		retailers.Retailer{ID: "target", Name: "Target", Aliases: []string{"Target.com"}, Category: "general-merchandise"},
+		retailers.Retailer{ID: "walgreens", Name: "Walgreens", Aliases: []string{"Walgreens Pharmacy"}, Category: "pharmacy"},
+		retailers.Retailer{ID: "m-and-m-corner-market", Name: "M&M Corner Market", Aliases: []string{"M&M Corner Store"}, Category: "convenience"},
+		retailers.Retailer{ID: "cvs", Name: "CVS", Category: "pharmacy"},
+		retailers.Retailer{ID: "cafe-lea", Name: "Cafe Lea", Category: "restaurant"},
+		retailers.Retailer{ID: "corner-shop-2", Name: "Corner Shop 2", Category: "convenience"},
+		retailers.Retailer{ID: "corner-shop-3", Name: "Corner Shop 3", Category: "convenience"},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error creating registry: %v", err)
+	}
+
+	for i, tc := range testCases {
+		retailer, ok := r.Match(tc.name)
+		if tc.wantID == "" && ok {
+			t.Errorf("Unexpected match in test case %d: %q matched %s", i+1, tc.name, retailer.ID)
+		} else if tc.wantID != "" && retailer.ID != tc.wantID {
+			t.Errorf("Wrong retailer matched in test case %d: expected %s, received %q", i+1, tc.wantID, retailer.ID)
+		}
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	type testCase struct {
+		json        string
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{json: `[{"id": "a", "name": "Target", "aliases": ["TARGET STORE"], "category": "general-merchandise"}]`},
+		{json: `[{"id": "a", "name": "Target", "aliases": ["target"]}]`},
+		{json: `[{"id": "a", "name": ""}]`, errExpected: true},
+		{json: `[{"id": "a", "name": "Target"}, {"id": "a", "name": "Walgreens"}]`, errExpected: true},
+		// two retailers can't share a name
+		{json: `[{"id": "a", "name": "Target"}, {"id": "b", "name": "Kroger", "aliases": ["T.A.R.G.E.T."]}]`, errExpected: true},
+		{json: `[{"id": "a", "name": "Target", "aliases": ["&&"]}]`, errExpected: true},
+		{json: `[{"id": "a", "name": "Target", "chain": "Target Corporation"}]`, errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		if _, err := retailers.LoadJSON(strings.NewReader(tc.json)); err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error loading retailers in test case %d: %v", i+1, err)
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	// the registry shipped with the service must load
+	r, err := retailers.LoadFile("../../data/retailers.json")
+	if err != nil {
+		t.Fatalf("Unexpected error loading retailers: %v", err)
+	}
+	if retailer, ok := r.Lookup("walgreens"); !ok || retailer.Category != "pharmacy" {
+		t.Errorf("Expected Walgreens to be a known pharmacy, received %+v", retailer)
+	}
+	if _, err := retailers.LoadFile(os.DevNull); err == nil {
+		t.Errorf("Did not receive expected error loading an empty file")
+	}
+}
@@ -2,17 +2,82 @@ package receipt_service
 
 import (
 	ctx "context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	auth "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/auth"
+	fraud "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/fraud"
+	ingest "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/ingest"
+	ledger "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/ledger"
+	limits "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/limits"
+	logging "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/logging"
+	metrics "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/metrics"
 	model "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+	promotions "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/promotions"
 )
 
+// tracer traces the RPCs whose work spans several steps, under the span of the gRPC call.
+var tracer = otel.Tracer("github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service")
+
+// DEFAULT_PREVIEW_DAYS is how far ahead expiring points are previewed, when not requested otherwise.
+const DEFAULT_PREVIEW_DAYS = 30
+
+// METHOD_SCOPES are the scopes API keys need to call each RPC; any others need the admin scope.
+var METHOD_SCOPES = map[string]auth.Scope{
+	pb.ReceiptService_ProcessReceipt_FullMethodName:        auth.Process,
+	pb.ReceiptService_ImportEmailReceipt_FullMethodName:    auth.Process,
+	pb.ReceiptService_AwardPoints_FullMethodName:           auth.Award,
+	pb.ReceiptService_GetBalance_FullMethodName:            auth.Award,
+	pb.ReceiptService_ListLedgerEntries_FullMethodName:     auth.Award,
+	pb.ReceiptService_RedeemPoints_FullMethodName:          auth.Award,
+	pb.ReceiptService_PreviewExpiringPoints_FullMethodName: auth.Award,
+	pb.ReceiptService_ReversePoints_FullMethodName:         auth.Admin,
+	pb.ReceiptService_ListFlaggedReceipts_FullMethodName:   auth.Admin,
+	pb.ReceiptService_ReviewReceipt_FullMethodName:         auth.Admin,
+}
+
+// PUBLIC_METHODS are the RPCs anyone may call without credentials or rate limits, so probes needn't hold an API key.
+var PUBLIC_METHODS = []string{
+	healthpb.Health_Check_FullMethodName,
+	healthpb.Health_Watch_FullMethodName,
+}
+
+// SUBMISSION_METHODS are the RPCs which submit receipts, counted against each client's daily quota.
+var SUBMISSION_METHODS = []string{
+	pb.ReceiptService_ProcessReceipt_FullMethodName,
+	pb.ReceiptService_ImportEmailReceipt_FullMethodName,
+}
+
 type ReceiptService struct {
 	pb.UnimplementedReceiptServiceServer
-	db *model.ReceiptDB
+	db         *model.ReceiptDB
+	proc       *model.Processor
+	ledger     *ledger.Ledger
+	promotions *promotions.Catalog
+	fraud      *fraud.Scorer
+	auth       *auth.Authenticator
+	metrics    *metrics.Metrics
+	logger     *slog.Logger
+	health     *health.Server
+	serverOpts []grpc.ServerOption
+	unary      []grpc.UnaryServerInterceptor
+	stream     []grpc.StreamServerInterceptor
 }
 
 func (s *ReceiptService) ProcessReceipt(ctx ctx.Context, req *pb.ProcessReceiptRequest) (res *pb.ProcessReceiptResponse, err error) {
@@ -22,47 +87,494 @@ func (s *ReceiptService) ProcessReceipt(ctx ctx.Context, req *pb.ProcessReceiptR
 		PurchaseTime: req.PurchaseTime,
 		Items:        req.Items,
 		Total:        req.Total,
+		TimeZone:     req.TimeZone,
+		Currency:     req.Currency,
+		UserId:       req.UserId,
 	}
-
-	if rec, err := model.ProcessReceipt(r); err != nil {
+	if r.UserId, err = actingUser(ctx, req.UserId); err != nil {
 		return &pb.ProcessReceiptResponse{}, err
-	} else if id, err := s.db.Create(&rec); err != nil {
+	}
+
+	if id, err := s.store(ctx, r); err != nil {
 		return &pb.ProcessReceiptResponse{}, err
 	} else {
 		return &pb.ProcessReceiptResponse{Id: id}, nil
 	}
 }
 
+func (s *ReceiptService) ImportEmailReceipt(ctx ctx.Context, req *pb.ImportEmailReceiptRequest) (res *pb.ImportEmailReceiptResponse, err error) {
+	// extract the e-receipt from the email, then process it like any other receipt
+	r, err := ingest.ParseEmail(req.Message)
+	if err != nil {
+		return &pb.ImportEmailReceiptResponse{}, err
+	}
+	if r.UserId, err = actingUser(ctx, req.UserId); err != nil {
+		return &pb.ImportEmailReceiptResponse{}, err
+	}
+	if id, err := s.store(ctx, r); err != nil {
+		return &pb.ImportEmailReceiptResponse{}, err
+	} else {
+		return &pb.ImportEmailReceiptResponse{Id: id, Receipt: r}, nil
+	}
+}
+
 func (s *ReceiptService) AwardPoints(ctx ctx.Context, req *pb.AwardPointsRequest) (res *pb.AwardPointsResponse, err error) {
+	ctx, span := tracer.Start(ctx, "ReceiptService.AwardPoints", trace.WithAttributes(attribute.String("receipt.id", req.Id)))
+	defer func() {
+		if err != nil {
+			span.SetStatus(otelcodes.Error, err.Error())
+		} else {
+			span.SetAttributes(attribute.Int64("points.total", res.GetTotal().GetPoints()))
+		}
+		span.End()
+	}()
 	// validate that the request actually contains an id of a processed receipt
-	if receipt, err := s.db.Get(req.Id); err != nil {
+	if receipt, err := s.db.Get(ctx, req.Id); err != nil {
 		return &pb.AwardPointsResponse{}, err
 	} else {
+		// users may only be awarded for their own receipts
+		if p, ok := auth.FromContext(ctx); ok && p.UserID != "" && p.UserID != receipt.UserID {
+			return &pb.AwardPointsResponse{}, status.Error(codes.PermissionDenied, "Receipt was submitted by another user")
+		}
 		// check if receipt was already awarded once
-		if receipt.Awarded {
+		if receipt.Held {
+			// points wait on a review of the receipt
+			return &pb.AwardPointsResponse{}, model.ErrBadRequest("Receipt is held for review; no points can be awarded yet")
+		} else if receipt.Awarded {
 			// bail out, award nothing
-			return &pb.AwardPointsResponse{Points: &pb.Points{Points: 0}}, nil
+			return &pb.AwardPointsResponse{Points: &pb.Points{Points: 0}, Total: &pb.Points{Points: 0}}, nil
 		} else {
-			// proceed with award
+			// proceed with award, plus any promotions running
 			award := model.AwardPoints(receipt)
+			res = &pb.AwardPointsResponse{Points: &pb.Points{Points: award}, Promotions: make([]*pb.PromotionAward, 0), Total: &pb.Points{Points: award}}
+			promoted := s.promotions.Apply(receipt, award)
+			for _, a := range promoted {
+				res.Promotions = append(res.Promotions, &pb.PromotionAward{PromotionId: a.PromotionID, Description: a.Description, Points: a.Points})
+				res.Total.Points += a.Points
+			}
+			// credit the submitting user's balance; a concurrent award of the same receipt loses here,
+			// & like any award not credited, returns its promotions' points to their budgets
+			if receipt.UserID != "" {
+				if _, err := s.ledger.Award(receipt.UserID, req.Id, res.Total.Points); errors.Is(err, ledger.ErrAlreadyAwarded) {
+					s.promotions.Refund(promoted)
+					return &pb.AwardPointsResponse{Points: &pb.Points{Points: 0}, Total: &pb.Points{Points: 0}}, nil
+				} else if err != nil {
+					s.promotions.Refund(promoted)
+					return &pb.AwardPointsResponse{}, model.ErrInternalServer(err.Error())
+				}
+			}
 			// flag the receipt as awarded
 			awardedReceipt := receipt
 			awardedReceipt.Awarded = true
-			if _, err := s.db.Set(req.Id, awardedReceipt); err != nil {
+			if _, err := s.db.Set(ctx, req.Id, awardedReceipt); err != nil {
+				// a user's points are credited once in the ledger, so their promotions' points were spent after all
+				if receipt.UserID == "" {
+					s.promotions.Refund(promoted)
+				}
 				return &pb.AwardPointsResponse{}, err
 			} else {
-				return &pb.AwardPointsResponse{Points: &pb.Points{Points: award}}, nil
+				s.metrics.ReceiptAwarded(res.Total.Points)
+				return res, nil
 			}
 		}
 	}
 }
 
-func NewService() (srv *grpc.Server) {
+func (s *ReceiptService) GetBalance(ctx ctx.Context, req *pb.GetBalanceRequest) (res *pb.GetBalanceResponse, err error) {
+	if req.Id, err = actingUser(ctx, req.Id); err != nil {
+		return &pb.GetBalanceResponse{}, err
+	} else if req.Id == "" {
+		return &pb.GetBalanceResponse{}, model.ErrBadRequest("No User ID was provided")
+	} else {
+		return &pb.GetBalanceResponse{UserId: req.Id, Balance: &pb.Points{Points: s.ledger.Balance(req.Id)}}, nil
+	}
+}
+
+func (s *ReceiptService) ListLedgerEntries(ctx ctx.Context, req *pb.ListLedgerEntriesRequest) (res *pb.ListLedgerEntriesResponse, err error) {
+	if req.Id, err = actingUser(ctx, req.Id); err != nil {
+		return &pb.ListLedgerEntriesResponse{}, err
+	} else if req.Id == "" {
+		return &pb.ListLedgerEntriesResponse{}, model.ErrBadRequest("No User ID was provided")
+	}
+	res = &pb.ListLedgerEntriesResponse{Entries: make([]*pb.LedgerEntry, 0), Balance: &pb.Points{Points: 0}}
+	for _, e := range s.ledger.Entries(req.Id) {
+		res.Entries = append(res.Entries, ledgerEntry(e))
+		res.Balance.Points = e.Balance
+	}
+	return res, nil
+}
+
+func (s *ReceiptService) RedeemPoints(ctx ctx.Context, req *pb.RedeemPointsRequest) (res *pb.RedeemPointsResponse, err error) {
+	if req.UserId, err = actingUser(ctx, req.UserId); err != nil {
+		return &pb.RedeemPointsResponse{}, err
+	}
+	if entry, err := s.ledger.Redeem(req.UserId, req.Points, req.IdempotencyKey, ledger.Reason(req.Reason)); err != nil {
+		return &pb.RedeemPointsResponse{}, ledgerError(err)
+	} else {
+		return &pb.RedeemPointsResponse{Entry: ledgerEntry(entry), Balance: &pb.Points{Points: s.ledger.Balance(req.UserId)}}, nil
+	}
+}
+
+func (s *ReceiptService) ReversePoints(ctx ctx.Context, req *pb.ReversePointsRequest) (res *pb.ReversePointsResponse, err error) {
+	// an award may be identified by its receipt, rather than its entry
+	entryID := req.EntryId
+	if entryID == "" && req.ReceiptId != "" {
+		if award, err := s.ledger.AwardFor(req.ReceiptId); err != nil {
+			return &pb.ReversePointsResponse{}, ledgerError(err)
+		} else {
+			entryID = award.ID
+		}
+	} else if entryID != "" && req.ReceiptId != "" {
+		return &pb.ReversePointsResponse{}, model.ErrBadRequest("Only one of an entry ID or a receipt ID may be reversed")
+	}
+
+	if entry, err := s.ledger.Reverse(entryID, req.IdempotencyKey, ledger.Reason(req.Reason)); err != nil {
+		return &pb.ReversePointsResponse{}, ledgerError(err)
+	} else {
+		return &pb.ReversePointsResponse{Entry: ledgerEntry(entry), Balance: &pb.Points{Points: s.ledger.Balance(entry.UserID)}}, nil
+	}
+}
+
+func (s *ReceiptService) PreviewExpiringPoints(ctx ctx.Context, req *pb.PreviewExpiringPointsRequest) (res *pb.PreviewExpiringPointsResponse, err error) {
+	if req.UserId, err = actingUser(ctx, req.UserId); err != nil {
+		return &pb.PreviewExpiringPointsResponse{}, err
+	} else if req.UserId == "" && len(req.ReceiptIds) == 0 {
+		return &pb.PreviewExpiringPointsResponse{}, model.ErrBadRequest("A User ID or Receipt IDs must be provided")
+	} else if req.Days < 0 {
+		return &pb.PreviewExpiringPointsResponse{}, model.ErrBadRequest("Days to preview may not be negative")
+	}
+	days := req.Days
+	if days == 0 {
+		days = DEFAULT_PREVIEW_DAYS
+	}
+
+	res = &pb.PreviewExpiringPointsResponse{Lots: make([]*pb.PointsLot, 0), Total: &pb.Points{Points: 0}}
+	for _, lot := range s.ledger.Expiring(req.UserId, req.ReceiptIds, time.Now().AddDate(0, 0, int(days))) {
+		res.Lots = append(res.Lots, &pb.PointsLot{
+			EntryId:   lot.EntryID,
+			UserId:    lot.UserID,
+			ReceiptId: lot.ReceiptID,
+			Points:    lot.Points,
+			AwardedAt: lot.AwardedAt.Format(time.RFC3339),
+			ExpiresAt: lot.ExpiresAt.Format(time.RFC3339),
+		})
+		res.Total.Points += lot.Points
+	}
+	return res, nil
+}
+
+func (s *ReceiptService) ListFlaggedReceipts(ctx ctx.Context, req *pb.ListFlaggedReceiptsRequest) (res *pb.ListFlaggedReceiptsResponse, err error) {
+	var outcome fraud.Outcome
+	if req.Outcome != "" {
+		if outcome, err = fraud.ParseOutcome(req.Outcome); err != nil {
+			return &pb.ListFlaggedReceiptsResponse{}, model.ErrBadRequest(err.Error())
+		}
+	}
+
+	res = &pb.ListFlaggedReceiptsResponse{Receipts: make([]*pb.FlaggedReceipt, 0)}
+	for _, a := range s.fraud.Flagged(outcome) {
+		res.Receipts = append(res.Receipts, &pb.FlaggedReceipt{
+			Id:         a.ReceiptID,
+			UserId:     a.UserID,
+			Retailer:   a.Retailer,
+			Outcome:    string(a.Outcome),
+			Score:      math.Round(a.Score*100) / 100,
+			Reasons:    a.Reasons(),
+			AssessedAt: a.AssessedAt.Format(time.RFC3339),
+		})
+	}
+	return res, nil
+}
+
+func (s *ReceiptService) ReviewReceipt(ctx ctx.Context, req *pb.ReviewReceiptRequest) (res *pb.ReviewReceiptResponse, err error) {
+	decision := strings.ToUpper(strings.TrimSpace(req.Decision))
+	if decision != "RELEASE" && decision != "REJECT" {
+		return &pb.ReviewReceiptResponse{}, model.ErrBadRequest(fmt.Sprintf("Unknown review decision %q: must be RELEASE or REJECT", req.Decision))
+	}
+	receipt, err := s.db.Get(ctx, req.Id)
+	if err != nil {
+		return &pb.ReviewReceiptResponse{}, err
+	}
+
+	if decision == "REJECT" {
+		// rejected receipts aren't kept, as if they'd been rejected when processed; awarded points are reversed instead
+		if receipt.Awarded {
+			return &pb.ReviewReceiptResponse{}, model.ErrBadRequest("Receipt was already awarded; reverse its points instead")
+		} else if err := s.db.Delete(ctx, req.Id); err != nil {
+			return &pb.ReviewReceiptResponse{}, err
+		}
+	} else if receipt.Held {
+		// released receipts are awarded as usual
+		released := *receipt
+		released.Held = false
+		if _, err := s.db.Set(ctx, req.Id, &released); err != nil {
+			return &pb.ReviewReceiptResponse{}, err
+		}
+	}
+	s.fraud.Reviewed(req.Id)
+	return &pb.ReviewReceiptResponse{Id: req.Id, Decision: decision}, nil
+}
+
+// ledgerError converts a ledger error to the service's error for it.
+func ledgerError(err error) error {
+	if errors.Is(err, ledger.ErrEntryNotFound) {
+		return model.ErrNotFound(err.Error())
+	} else {
+		return model.ErrBadRequest(err.Error())
+	}
+}
+
+// ledgerEntry converts a ledger Entry to its API representation.
+func ledgerEntry(e ledger.Entry) *pb.LedgerEntry {
+	return &pb.LedgerEntry{
+		Id:        e.ID,
+		UserId:    e.UserID,
+		ReceiptId: e.ReceiptID,
+		Kind:      string(e.Kind),
+		Points:    e.Points,
+		Balance:   e.Balance,
+		PostedAt:  e.PostedAt.Format(time.RFC3339),
+		Reason:    string(e.Reason),
+		Reverses:  e.Reverses,
+	}
+}
+
+// store validates a receipt, scores it for fraud & saves it unless rejected, returning its newly generated id
+func (s *ReceiptService) store(ctx ctx.Context, r *pb.Receipt) (id string, err error) {
+	rec, err := s.proc.ProcessReceipt(ctx, r)
+	if err != nil {
+		s.metrics.ValidationFailed(err)
+		return "", err
+	}
+
+	assessment := s.fraud.Screen(&rec)
+	if assessment.Outcome == fraud.Reject {
+		s.fraud.Record("", assessment)
+		// the signals matched are kept for reviewers, not told to the submitter
+		return "", model.ErrBadRequest("Receipt was rejected as likely fraudulent")
+	}
+	rec.Held = assessment.Outcome == fraud.Hold
+
+	if id, err = s.db.Create(ctx, &rec); err != nil {
+		return "", err
+	}
+	s.fraud.Record(id, assessment)
+	s.metrics.ReceiptStored()
+	return id, nil
+}
+
+// An Option configures the Receipt Service.
+type Option func(s *ReceiptService)
+
+// WithStore stores receipts in an existing store, such as one the caller closes on shutdown.
+func WithStore(db *model.ReceiptDB) Option {
+	return func(s *ReceiptService) {
+		s.db = db
+	}
+}
+
+// WithRates converts receipt item prices into the base currency points are computed in.
+func WithRates(rates model.RateProvider) Option {
+	return func(s *ReceiptService) {
+		s.proc.Rates = rates
+	}
+}
+
+// WithLedger records awarded points in an existing ledger, such as one whose expiry is scheduled by the caller.
+func WithLedger(l *ledger.Ledger) Option {
+	return func(s *ReceiptService) {
+		s.ledger = l
+	}
+}
+
+// WithPromotions awards points on top of each receipt's base score, for the promotions it qualifies for.
+func WithPromotions(c *promotions.Catalog) Option {
+	return func(s *ReceiptService) {
+		s.promotions = c
+	}
+}
+
+// WithRetailers matches receipts to known retailers, recording their canonical ids.
+func WithRetailers(r model.RetailerResolver) Option {
+	return func(s *ReceiptService) {
+		s.proc.Retailers = r
+	}
+}
+
+// WithItemClassifier identifies the brand & product category of each item on the receipts processed.
+func WithItemClassifier(c model.ItemClassifier) Option {
+	return func(s *ReceiptService) {
+		s.proc.Items = c
+	}
+}
+
+// WithFraudScorer replaces the default fraud scoring of receipts as they're processed.
+func WithFraudScorer(scorer *fraud.Scorer) Option {
+	return func(s *ReceiptService) {
+		s.fraud = scorer
+	}
+}
+
+// WithValidationPolicy replaces the default limits receipts must satisfy before they are stored.
+func WithValidationPolicy(policy model.ValidationPolicy) Option {
+	return func(s *ReceiptService) {
+		s.proc.Policy = &policy
+	}
+}
+
+// WithDateLayouts replaces the layouts purchase dates are recognized in, such as model.ISODateLayouts.
+func WithDateLayouts(layouts ...string) Option {
+	return func(s *ReceiptService) {
+		s.proc.DateLayouts = layouts
+	}
+}
+
+// WithTimeLayouts replaces the layouts purchase times are recognized in, such as model.TwentyFourHourTimeLayouts.
+func WithTimeLayouts(layouts ...string) Option {
+	return func(s *ReceiptService) {
+		s.proc.TimeLayouts = layouts
+	}
+}
+
+// WithServerOptions configures the gRPC server the service is registered on, such as with its transport credentials.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(s *ReceiptService) {
+		s.serverOpts = append(s.serverOpts, opts...)
+	}
+}
+
+// WithAPIKeys requires callers to present an API key granting the scope of each RPC they call,
+// or a bearer token when WithBearerTokens is also given.
+func WithAPIKeys(keys *auth.KeyStore) Option {
+	return func(s *ReceiptService) {
+		s.authenticator().Keys = keys
+	}
+}
+
+// WithBearerTokens accepts bearer tokens as well as, or instead of, API keys.
+// A token's subject may submit receipts & be awarded points for them, acting only as themselves.
+func WithBearerTokens(v *auth.TokenVerifier) Option {
+	return func(s *ReceiptService) {
+		s.authenticator().Tokens = v
+	}
+}
+
+// WithRateLimits limits the RPCs each client makes, & the receipts they submit each day.
+func WithRateLimits(l *limits.Limiter) Option {
+	return func(s *ReceiptService) {
+		s.unary = append(s.unary, l.UnaryInterceptor(PUBLIC_METHODS, SUBMISSION_METHODS))
+		s.stream = append(s.stream, l.StreamInterceptor(PUBLIC_METHODS))
+	}
+}
+
+// WithMetrics records the service's RPCs, receipts & points awarded, validation failures & receipt store lock waits.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *ReceiptService) {
+		s.metrics = m
+	}
+}
+
+// WithLogger logs each RPC with its request id, & the receipts rejected, to a structured logger.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *ReceiptService) {
+		s.logger = l
+		s.proc.Logger = l
+	}
+}
+
+// WithHealth reports the service's health through an existing health server,
+// such as one the caller marks NOT_SERVING on shutdown, so connections drain before it stops.
+func WithHealth(h *health.Server) Option {
+	return func(s *ReceiptService) {
+		s.health = h
+	}
+}
+
+// authenticator returns the service's Authenticator, creating it on first use.
+func (s *ReceiptService) authenticator() *auth.Authenticator {
+	if s.auth == nil {
+		s.auth = &auth.Authenticator{Methods: METHOD_SCOPES, Public: PUBLIC_METHODS}
+	}
+	return s.auth
+}
+
+// actingUser returns the user an RPC acts on behalf of: the user a bearer token authenticated,
+// who may not act as anyone else, or otherwise the requested user.
+func actingUser(ctx ctx.Context, requested string) (userID string, err error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.UserID == "" {
+		return requested, nil
+	} else if requested != "" && requested != p.UserID {
+		return "", status.Errorf(codes.PermissionDenied, "%s may not act as user %s", p.ID, requested)
+	}
+	return p.UserID, nil
+}
+
+func NewService(opts ...Option) (srv *grpc.Server) {
+	// configure the service
+	s := &ReceiptService{
+		db:     &model.ReceiptDB{Store: make(map[string]*model.Receipt)},
+		proc:   model.NewProcessor(),
+		ledger: ledger.New(),
+		fraud:  fraud.NewScorer(fraud.DefaultSignals()...),
+		health: health.NewServer(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// callers are authenticated before anything else, so other interceptors know who they are
+	if s.auth != nil {
+		s.unary = append([]grpc.UnaryServerInterceptor{s.auth.UnaryInterceptor()}, s.unary...)
+		s.stream = append([]grpc.StreamServerInterceptor{s.auth.StreamInterceptor()}, s.stream...)
+	}
+	// & given a request id & logged before that, so RPCs rejected by the other interceptors are logged too
+	if s.logger != nil {
+		s.unary = append([]grpc.UnaryServerInterceptor{logging.UnaryInterceptor(s.logger)}, s.unary...)
+		s.stream = append([]grpc.StreamServerInterceptor{logging.StreamInterceptor(s.logger)}, s.stream...)
+	}
+	// & counted before that, so RPCs rejected by the other interceptors are counted too
+	if s.metrics != nil {
+		s.unary = append([]grpc.UnaryServerInterceptor{s.metrics.UnaryInterceptor()}, s.unary...)
+		s.stream = append([]grpc.StreamServerInterceptor{s.metrics.StreamInterceptor()}, s.stream...)
+		s.db.LockWait = s.metrics.LockWait
+	}
 	// create the server
-	srv = grpc.NewServer()
+	srv = grpc.NewServer(append(s.serverOpts, grpc.ChainUnaryInterceptor(s.unary...), grpc.ChainStreamInterceptor(s.stream...))...)
 	// put it all together & register
-	pb.RegisterReceiptServiceServer(srv, &ReceiptService{db: &model.ReceiptDB{Store: make(map[string]*model.Receipt)}})
+	pb.RegisterReceiptServiceServer(srv, s)
+	// report the service serving while its store is ready, under its own name & the server's
+	checker := &healthChecker{Server: s.health, db: s.db}
+	healthpb.RegisterHealthServer(srv, checker)
+	checker.refresh()
 	// enable server reflection
 	reflection.Register(srv)
 	return srv
 }
+
+// healthChecker reports the Receipt Service serving while its store is ready, checking it afresh on each health check,
+// so a store which closes or recovers is reported as such. Once the health server is shut down, it stays NOT_SERVING.
+type healthChecker struct {
+	*health.Server
+	db *model.ReceiptDB
+}
+
+func (h *healthChecker) Check(ctx ctx.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	h.refresh()
+	return h.Server.Check(ctx, req)
+}
+
+func (h *healthChecker) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	// watchers are sent the status as it is now, & then whenever a check finds it's changed
+	h.refresh()
+	return h.Server.Watch(req, stream)
+}
+
+// refresh sets the serving status of the service, & the server as a whole, from whether the store is ready.
+func (h *healthChecker) refresh() {
+	serving := healthpb.HealthCheckResponse_SERVING
+	if err := h.db.Ready(); err != nil {
+		serving = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	h.SetServingStatus(pb.ReceiptService_ServiceDesc.ServiceName, serving)
+	h.SetServingStatus("", serving)
+}
@@ -0,0 +1,271 @@
+package receipt_service_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	receipt_service "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/fraud"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/ledger"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/limits"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/promotions"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/retailers"
+)
+
+// newTestClient serves the Receipt Service on a local port until the test ends, returning a client connected to it.
+func newTestClient(t *testing.T, opts ...receipt_service.Option) (conn *grpc.ClientConn) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error listening: %v", err)
+	}
+	srv := receipt_service.NewService(opts...)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err = grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Unexpected error dialing server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// holdAll is a fraud Signal holding every receipt for review.
+type holdAll struct{}
+
+func (holdAll) Name() string { return "hold-all" }
+
+func (holdAll) Score(r *model.Receipt) (score float64, reason string) {
+	return fraud.DefaultHoldAt, "always"
+}
+
+// processReceipt processes a receipt from a retailer with a single item, returning its id.
+func processReceipt(client pb.ReceiptServiceClient, retailer string, item string) (id string, err error) {
+	return processUserReceipt(client, "", retailer, item)
+}
+
+// processUserReceipt processes a receipt a user submitted, from a retailer with a single item, returning its id.
+func processUserReceipt(client pb.ReceiptServiceClient, userID string, retailer string, item string) (id string, err error) {
+	res, err := client.ProcessReceipt(context.Background(), &pb.ProcessReceiptRequest{
+		UserId:       userID,
+		Retailer:     retailer,
+		PurchaseDate: "2022-01-01",
+		PurchaseTime: "13:01",
+		Items:        []*pb.Item{{ShortDescription: item, Price: "2.25"}},
+		Total:        "2.25",
+	})
+	return res.GetId(), err
+}
+
+func TestReceiptService_AwardPoints_Promotions(t *testing.T) {
+	type testCase struct {
+		retailer       string
+		wantPromotions []string
+	}
+
+	registry, err := retailers.LoadFile("../data/retailers.json")
+	if err != nil {
+		t.Fatalf("Unexpected error loading retailers: %v", err)
+	}
+	catalog, err := promotions.LoadFile("../data/promotions.json")
+	if err != nil {
+		t.Fatalf("Unexpected error loading promotions: %v", err)
+	}
+	client := pb.NewReceiptServiceClient(newTestClient(t, receipt_service.WithRetailers(registry), receipt_service.WithPromotions(catalog)))
+
+	// promotions for a retailer apply to receipts naming any of its aliases
+	var testCases = []testCase{
+		{retailer: "Walgreens", wantPromotions: []string{"walgreens-double-weekend", "gatorade-bonus"}},
+		{retailer: "WALGREENS PHARMACY", wantPromotions: []string{"walgreens-double-weekend", "gatorade-bonus"}},
+		{retailer: "Walgreen Co", wantPromotions: []string{"walgreens-double-weekend", "gatorade-bonus"}},
+		{retailer: "Target", wantPromotions: []string{}},
+	}
+
+	for i, tc := range testCases {
+		id, err := processReceipt(client, tc.retailer, "Gatorade")
+		if err != nil {
+			t.Fatalf("Unexpected error processing receipt in test case %d: %v", i+1, err)
+		}
+		res, err := client.AwardPoints(context.Background(), &pb.AwardPointsRequest{Id: id})
+		if err != nil {
+			t.Fatalf("Unexpected error awarding points in test case %d: %v", i+1, err)
+		}
+		applied := make([]string, 0)
+		for _, p := range res.Promotions {
+			applied = append(applied, p.PromotionId)
+		}
+		if len(applied) != len(tc.wantPromotions) {
+			t.Errorf("Wrong promotions applied in test case %d: expected %v, received %v", i+1, tc.wantPromotions, applied)
+			continue
+		}
+		for j, want := range tc.wantPromotions {
+			if applied[j] != want {
+				t.Errorf("Wrong promotions applied in test case %d: expected %v, received %v", i+1, tc.wantPromotions, applied)
+				break
+			}
+		}
+	}
+}
+
+func TestReceiptService_AwardPoints_PromotionBudget(t *testing.T) {
+	catalog, err := promotions.NewCatalog(promotions.Promotion{
+		ID: "gatorade-bonus", Retailer: "Walgreens", Item: "Gatorade", Bonus: 500, Starts: "2022-01-01", Ends: "2022-12-31", Budget: 500,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating catalog: %v", err)
+	}
+	l := ledger.New()
+	client := pb.NewReceiptServiceClient(newTestClient(t, receipt_service.WithPromotions(catalog), receipt_service.WithLedger(l)))
+
+	// an award which loses to a concurrent one credits nothing, so spends none of the budget
+	first, err := processUserReceipt(client, "alice", "Walgreens", "Gatorade")
+	if err != nil {
+		t.Fatalf("Unexpected error processing receipt: %v", err)
+	}
+	l.Award("alice", first, 10)
+	if res, err := client.AwardPoints(context.Background(), &pb.AwardPointsRequest{Id: first}); err != nil || res.Total.GetPoints() != 0 {
+		t.Fatalf("Expected nothing awarded for a receipt already credited, received %v, %v", res, err)
+	}
+
+	second, err := processUserReceipt(client, "alice", "Walgreens", "Gatorade")
+	if err != nil {
+		t.Fatalf("Unexpected error processing receipt: %v", err)
+	}
+	if res, err := client.AwardPoints(context.Background(), &pb.AwardPointsRequest{Id: second}); err != nil || len(res.Promotions) != 1 || res.Promotions[0].Points != 500 {
+		t.Errorf("Expected the full promotion budget to be awarded, received %v, %v", res, err)
+	}
+}
+
+func TestReceiptService_ReviewReceipt(t *testing.T) {
+	type testCase struct {
+		decision    string
+		errExpected bool
+		wantAward   bool // whether the receipt can be awarded after review
+	}
+
+	scorer := fraud.NewScorer(holdAll{})
+	client := pb.NewReceiptServiceClient(newTestClient(t, receipt_service.WithFraudScorer(scorer)))
+
+	var testCases = []testCase{
+		{decision: "RELEASE", wantAward: true},
+		{decision: "reject"},
+		{decision: "APPROVE", errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		id, err := processReceipt(client, "Target", "Mountain Dew 12PK")
+		if err != nil {
+			t.Fatalf("Unexpected error processing receipt in test case %d: %v", i+1, err)
+		}
+		// held receipts can't be awarded until they're reviewed
+		if _, err := client.AwardPoints(context.Background(), &pb.AwardPointsRequest{Id: id}); err == nil {
+			t.Errorf("Did not receive expected error awarding held receipt in test case %d", i+1)
+		}
+
+		_, err = client.ReviewReceipt(context.Background(), &pb.ReviewReceiptRequest{Id: id, Decision: tc.decision})
+		if err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error reviewing receipt in test case %d: %v", i+1, err)
+			continue
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+			continue
+		} else if err != nil {
+			continue
+		}
+		for _, a := range scorer.Flagged("") {
+			if a.ReceiptID == id {
+				t.Errorf("Reviewed receipt still listed for review in test case %d", i+1)
+			}
+		}
+		res, err := client.AwardPoints(context.Background(), &pb.AwardPointsRequest{Id: id})
+		if tc.wantAward && (err != nil || res.Total.GetPoints() == 0) {
+			t.Errorf("Released receipt was not awarded in test case %d: %v", i+1, err)
+		} else if !tc.wantAward && err == nil {
+			t.Errorf("Rejected receipt was awarded in test case %d", i+1)
+		}
+	}
+}
+
+func TestReceiptService_ProcessReceipt_Layouts(t *testing.T) {
+	type testCase struct {
+		opts        []receipt_service.Option
+		date        string
+		time        string
+		wantDate    string
+		wantTime    string
+		errExpected bool
+	}
+
+	var testCases = []testCase{
+		{date: "2022-01-02", time: "1:01 PM", wantDate: "2022-01-02", wantTime: "13:01"},
+		// custom layouts replace the defaults
+		{opts: []receipt_service.Option{receipt_service.WithDateLayouts("20060102")}, date: "20220102", time: "13:01", wantDate: "2022-01-02", wantTime: "13:01"},
+		{opts: []receipt_service.Option{receipt_service.WithTimeLayouts("15.04")}, date: "2022-01-02", time: "13.01", wantDate: "2022-01-02", wantTime: "13:01"},
+		{opts: []receipt_service.Option{receipt_service.WithDateLayouts("20060102")}, date: "1/2/2022", time: "13:01", errExpected: true},
+		{opts: []receipt_service.Option{receipt_service.WithTimeLayouts(model.TwentyFourHourTimeLayouts...)}, date: "2022-01-02", time: "1:01 PM", errExpected: true},
+		// without custom layouts, neither is recognized
+		{date: "20220102", time: "13:01", errExpected: true},
+		{date: "2022-01-02", time: "13.01", errExpected: true},
+	}
+
+	for i, tc := range testCases {
+		store := &model.ReceiptDB{Store: make(map[string]*model.Receipt)}
+		client := pb.NewReceiptServiceClient(newTestClient(t, append(tc.opts, receipt_service.WithStore(store))...))
+		res, err := client.ProcessReceipt(context.Background(), &pb.ProcessReceiptRequest{
+			Retailer:     "Target",
+			PurchaseDate: tc.date,
+			PurchaseTime: tc.time,
+			Items:        []*pb.Item{{ShortDescription: "Mountain Dew 12PK", Price: "6.49"}},
+			Total:        "6.49",
+		})
+		if err != nil && !tc.errExpected {
+			t.Errorf("Unexpected error in test case %d: %v", i+1, err)
+			continue
+		} else if err == nil && tc.errExpected {
+			t.Errorf("Did not receive expected error in test case %d", i+1)
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		if r, err := store.Get(context.Background(), res.GetId()); err != nil {
+			t.Errorf("Unexpected error getting receipt in test case %d: %v", i+1, err)
+		} else if r.Date != tc.wantDate || r.Time != tc.wantTime {
+			t.Errorf("Wrong purchase date & time in test case %d: expected %s %s, received %s %s", i+1, tc.wantDate, tc.wantTime, r.Date, r.Time)
+		}
+	}
+}
+
+func TestReceiptService_Health(t *testing.T) {
+	store := &model.ReceiptDB{Store: make(map[string]*model.Receipt)}
+	// a single request a minute, which health checks are exempt from
+	limiter, err := limits.NewLimiter(limits.Policy{Default: limits.Limit{PerSecond: 1.0 / 60, Burst: 1}})
+	if err != nil {
+		t.Fatalf("Unexpected error creating limiter: %v", err)
+	}
+	client := healthpb.NewHealthClient(newTestClient(t, receipt_service.WithStore(store), receipt_service.WithRateLimits(limiter)))
+	check := func() (healthpb.HealthCheckResponse_ServingStatus, error) {
+		res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: pb.ReceiptService_ServiceDesc.ServiceName})
+		return res.GetStatus(), err
+	}
+
+	for i := 0; i < 3; i++ {
+		if status, err := check(); err != nil || status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("Service not reported serving on check %d: %s, %v", i+1, status, err)
+		}
+	}
+	// the store is checked afresh each time
+	if err := store.Close(context.Background()); err != nil {
+		t.Fatalf("Unexpected error closing store: %v", err)
+	}
+	if status, err := check(); err != nil || status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Service not reported NOT_SERVING once its store closed: %s, %v", status, err)
+	}
+}
@@ -0,0 +1,125 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+
+	config "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/config"
+)
+
+// A Closer releases something the servers used, such as the receipt store, once they've stopped.
+type Closer func(ctx context.Context) error
+
+// A Supervisor runs the gRPC server & its HTTP gateway together until it's stopped or either fails,
+// then tears them down in order: the gateway, the gRPC server, then what they used.
+type Supervisor struct {
+	Server          *grpc.Server
+	ServerListener  net.Listener
+	Gateway         *http.Server // Served over TLS when it has a TLSConfig.
+	GatewayListener net.Listener
+	Health          *health.Server // Optional. Marked NOT_SERVING as shutdown begins.
+	Closers         []Closer       // Closed in order, once both servers have stopped.
+	Timeout         time.Duration  // Most time in-flight requests are given to finish, & closers after them; unbounded when zero.
+	Logger          *slog.Logger   // Defaults to slog's default logger.
+}
+
+// Run serves until the context is done, such as on SIGINT or SIGTERM, or either server fails,
+// then shuts both down, returning why the servers failed or couldn't be shut down cleanly.
+func (s *Supervisor) Run(ctx context.Context) (err error) {
+	failed := make(chan error, 2)
+	go func() {
+		s.logger().Info("gRPC Server starting", "addr", s.ServerListener.Addr().String())
+		if err := s.Server.Serve(s.ServerListener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			failed <- fmt.Errorf("gRPC server failed: %w", err)
+		}
+	}()
+	go func() {
+		var err error
+		if s.Gateway.TLSConfig != nil {
+			s.logger().Info("Serving Receipt Service REST API via gRPC-Gateway", "url", "https://"+config.DialAddr(s.GatewayListener.Addr().String()))
+			err = s.Gateway.ServeTLS(s.GatewayListener, "", "")
+		} else {
+			s.logger().Info("Serving Receipt Service REST API via gRPC-Gateway", "url", "http://"+config.DialAddr(s.GatewayListener.Addr().String()))
+			err = s.Gateway.Serve(s.GatewayListener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failed <- fmt.Errorf("gateway failed: %w", err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		s.logger().Info("Shutting down server...")
+	case err = <-failed:
+		s.logger().Error("Shutting down server...", "error", err)
+	}
+	return errors.Join(err, s.shutdown())
+}
+
+// shutdown stops the gateway, then the gRPC server, each stopped outright if its requests outlast the deadline,
+// then closes what they used with a deadline of its own, so a slow drain doesn't cut a flush short.
+func (s *Supervisor) shutdown() (err error) {
+	// stop reporting ready first, so load balancers stop sending requests while those in flight finish
+	if s.Health != nil {
+		s.Health.Shutdown()
+	}
+
+	c, cancel := s.deadline()
+	defer cancel()
+	// the gateway's requests wait on the gRPC server, so it's drained first
+	if err := s.Gateway.Shutdown(c); err != nil {
+		s.logger().Warn("Gateway requests outlasted the shutdown deadline; closing their connections", "error", err)
+		s.Gateway.Close()
+	}
+	stopped := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-c.Done():
+		s.logger().Warn("gRPC requests outlasted the shutdown deadline; stopping the server outright")
+		s.Server.Stop()
+		<-stopped
+	}
+	s.logger().Info("Server has been shut down.")
+
+	c, cancel = s.deadline()
+	defer cancel()
+	for _, closer := range s.Closers {
+		if cerr := closer(c); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}
+	if err != nil {
+		s.logger().Error("Failed to close cleanly after shutdown", "error", err)
+	} else {
+		s.logger().Info("Graceful shutdown completed")
+	}
+	return err
+}
+
+// deadline returns a context done after the shutdown timeout, or never without one.
+func (s *Supervisor) deadline() (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), s.Timeout)
+}
+
+// logger returns the logger the supervisor reports startup & shutdown to.
+func (s *Supervisor) logger() *slog.Logger {
+	if s.Logger == nil {
+		return slog.Default()
+	}
+	return s.Logger
+}
@@ -0,0 +1,251 @@
+package supervisor_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/api/proto"
+	receipt_service "github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/service/model"
+	"github.com/ashyrae/fetch-receipt-processor-challenge/receipt-processor/supervisor"
+)
+
+const receiptJSON = `{"retailer": "Target", "purchaseDate": "2022-01-01", "purchaseTime": "13:01",
+	"items": [{"shortDescription": "Mountain Dew 12PK", "price": "6.49"}], "total": "6.49"}`
+
+// testServers are the Receipt Service & its gateway on local ports, supervised, with requests sent
+// with a "hold" header held before they reach the gateway until released.
+type testServers struct {
+	sup     *supervisor.Supervisor
+	store   *model.ReceiptDB
+	health  *health.Server
+	url     string
+	held    chan struct{}
+	release chan struct{}
+	closed  []string
+	sync.Mutex
+}
+
+func newTestServers(t *testing.T, timeout time.Duration) *testServers {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error listening: %v", err)
+	}
+	gwLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error listening: %v", err)
+	}
+
+	ts := &testServers{
+		store:   &model.ReceiptDB{Store: make(map[string]*model.Receipt)},
+		health:  health.NewServer(),
+		url:     "http://" + gwLis.Addr().String(),
+		held:    make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	srv := receipt_service.NewService(receipt_service.WithStore(ts.store), receipt_service.WithHealth(ts.health))
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Unexpected error dialing server: %v", err)
+	}
+	gwmux := runtime.NewServeMux(runtime.WithMarshalerOption("application/json", &runtime.JSONPb{}))
+	if err := pb.RegisterReceiptServiceHandler(context.Background(), gwmux, conn); err != nil {
+		t.Fatalf("Unexpected error registering gateway: %v", err)
+	}
+	gateway := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Hold") != "" {
+			ts.held <- struct{}{}
+			<-ts.release
+		}
+		gwmux.ServeHTTP(w, r)
+	})}
+
+	ts.sup = &supervisor.Supervisor{
+		Server:          srv,
+		ServerListener:  lis,
+		Gateway:         gateway,
+		GatewayListener: gwLis,
+		Health:          ts.health,
+		Closers: []supervisor.Closer{
+			func(context.Context) error {
+				ts.closing("conn")
+				return conn.Close()
+			},
+			func(ctx context.Context) error {
+				ts.closing("store")
+				return ts.store.Close(ctx)
+			},
+		},
+		Timeout: timeout,
+	}
+	return ts
+}
+
+// closing records what's closed, in order.
+func (ts *testServers) closing(name string) {
+	ts.Lock()
+	defer ts.Unlock()
+	ts.closed = append(ts.closed, name)
+}
+
+// closedSoFar returns what's been closed, in order.
+func (ts *testServers) closedSoFar() []string {
+	ts.Lock()
+	defer ts.Unlock()
+	return slices.Clone(ts.closed)
+}
+
+// run supervises the servers until the returned function is called, which returns what Run did.
+func (ts *testServers) run() (stop func() error) {
+	c, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ts.sup.Run(c) }()
+	return func() error {
+		cancel()
+		return <-done
+	}
+}
+
+// do sends a request to the gateway, holding it when asked, & returns its response's status code & body.
+func (ts *testServers) do(method string, path string, body string, hold bool) (code int, res map[string]any, err error) {
+	req, _ := http.NewRequest(method, ts.url+path, strings.NewReader(body))
+	if hold {
+		req.Header.Set("Hold", "1")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	return resp.StatusCode, res, err
+}
+
+// accepting reports whether the gateway accepts requests.
+func (ts *testServers) accepting() bool {
+	resp, err := http.Get(ts.url + "/")
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err == nil
+}
+
+// servingStatus returns what the health server reports of the Receipt Service.
+func (ts *testServers) servingStatus() healthpb.HealthCheckResponse_ServingStatus {
+	res, _ := ts.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: pb.ReceiptService_ServiceDesc.ServiceName})
+	return res.GetStatus()
+}
+
+// eventually waits up to a second for a condition to hold.
+func eventually(condition func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return condition()
+}
+
+func TestSupervisor_Run(t *testing.T) {
+	ts := newTestServers(t, 5*time.Second)
+	stop := ts.run()
+
+	if !eventually(ts.accepting) {
+		t.Fatalf("Gateway did not start serving")
+	}
+	code, res, err := ts.do(http.MethodPost, "/receipts/process", receiptJSON, false)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("Unexpected error processing receipt: %d, %v", code, err)
+	}
+	id, _ := res["id"].(string)
+	if ts.servingStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Service not reported serving before shutdown: %s", ts.servingStatus())
+	}
+
+	// a request in flight when shutdown begins is allowed to finish
+	type result struct {
+		code   int
+		points any
+		err    error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		code, res, err := ts.do(http.MethodGet, "/receipts/"+id+"/points", "", true)
+		inFlight <- result{code: code, points: res["total"], err: err}
+	}()
+	<-ts.held
+	stopped := make(chan error, 1)
+	go func() { stopped <- stop() }()
+
+	if !eventually(func() bool { return ts.servingStatus() == healthpb.HealthCheckResponse_NOT_SERVING }) {
+		t.Errorf("Service not reported NOT_SERVING during shutdown: %s", ts.servingStatus())
+	}
+	if !eventually(func() bool { return !ts.accepting() }) {
+		t.Errorf("Gateway accepted new requests during shutdown")
+	}
+	if closed := ts.closedSoFar(); len(closed) > 0 {
+		t.Errorf("Closed %v before in-flight requests finished", closed)
+	}
+
+	close(ts.release)
+	if r := <-inFlight; r.err != nil || r.code != http.StatusOK {
+		t.Errorf("In-flight request failed during shutdown: %d, %v", r.code, r.err)
+	} else if r.points == nil {
+		t.Errorf("In-flight request was not awarded points")
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("Unexpected error shutting down: %v", err)
+	}
+
+	// the store is closed last, once nothing is using it
+	if want := []string{"conn", "store"}; !slices.Equal(ts.closedSoFar(), want) {
+		t.Errorf("Wrong teardown order: expected %v, received %v", want, ts.closedSoFar())
+	}
+	if err := ts.store.Ready(); err == nil {
+		t.Errorf("Store still ready after shutdown")
+	}
+}
+
+func TestSupervisor_Run_Deadline(t *testing.T) {
+	ts := newTestServers(t, 100*time.Millisecond)
+	stop := ts.run()
+	defer close(ts.release)
+
+	if !eventually(ts.accepting) {
+		t.Fatalf("Gateway did not start serving")
+	}
+	inFlight := make(chan error, 1)
+	go func() {
+		_, _, err := ts.do(http.MethodPost, "/receipts/process", receiptJSON, true)
+		inFlight <- err
+	}()
+	<-ts.held
+
+	// requests outlasting the deadline are cut off, & everything is still closed
+	start := time.Now()
+	if err := stop(); err != nil {
+		t.Errorf("Unexpected error shutting down: %v", err)
+	} else if took := time.Since(start); took > time.Second {
+		t.Errorf("Shutdown outlasted its deadline: took %s", took)
+	}
+	if err := <-inFlight; err == nil {
+		t.Errorf("Request outlasting the deadline was not cut off")
+	}
+	if want := []string{"conn", "store"}; !slices.Equal(ts.closedSoFar(), want) {
+		t.Errorf("Wrong teardown order: expected %v, received %v", want, ts.closedSoFar())
+	}
+}